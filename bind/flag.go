@@ -15,7 +15,6 @@ import (
 
 	"github.com/mmatczuk/anyflag"
 	"github.com/saucelabs/forwarder"
-	"github.com/saucelabs/forwarder/fileurl"
 	"github.com/saucelabs/forwarder/header"
 	"github.com/saucelabs/forwarder/httplog"
 	"github.com/saucelabs/forwarder/log"
@@ -51,10 +50,18 @@ func DNSConfig(fs *pflag.FlagSet, cfg *osdns.Config) {
 	fs.BoolVar(&cfg.RoundRobin, "dns-round-robin", cfg.RoundRobin,
 		"If more than one DNS server is specified with the --dns-server flag, "+
 			"passing this flag will enable round-robin selection. ")
+
+	fs.Var(anyflag.NewSliceValue[string](cfg.Search, &cfg.Search, forwarder.ParseDNSSearchDomain),
+		"dns-search-domains", "<domain>"+
+			"DNS search domains to use instead of system default. "+
+			"When resolving a name with fewer dots than the system ndots option (usually 1), "+
+			"the search domains are appended to the name in order until the name is resolved. "+
+			"When set, changes to the system DNS configuration (/etc/resolv.conf) are not reloaded, "+
+			"including the DNS servers. ")
 }
 
 func PAC(fs *pflag.FlagSet, pac **url.URL) {
-	fs.VarP(anyflag.NewValue[*url.URL](*pac, pac, fileurl.ParseFilePathOrURL),
+	fs.VarP(anyflag.NewValue[*url.URL](*pac, pac, forwarder.ParseReadURL),
 		"pac", "p", "`<path or URL>`"+
 			"Proxy Auto-Configuration file to use for upstream proxy selection. "+
 			"It can be a local file or a URL, you can also use '-' to read from stdin. "+
@@ -117,7 +124,8 @@ func HTTPProxyConfig(fs *pflag.FlagSet, cfg *forwarder.HTTPProxyConfig, lcfg *lo
 		"proxy-localhost", "", "<allow|deny|direct>"+
 			"Setting this to allow enables sending requests to localhost through the upstream proxy. "+
 			"Setting this to direct sends requests to localhost directly without using the upstream proxy. "+
-			"By default, requests to localhost are denied. ")
+			"By default, requests to localhost are denied. "+
+			"Localhost is localhost, any name under the .localhost domain, and loopback addresses i.e. 127.0.0.0/8 and ::1. ")
 
 	fs.StringVar(&cfg.Name, "name", cfg.Name, "<string>"+
 		"Name of this proxy instance. This value is used in the Via header in requests. "+
@@ -135,6 +143,11 @@ func HTTPProxyConfig(fs *pflag.FlagSet, cfg *forwarder.HTTPProxyConfig, lcfg *lo
 	fs.Var(&cfg.WriteLimit, "write-limit", "<bandwidth>"+
 		"Global write rate limit in bytes per second i.e. how many bytes per second you can send to proxy. "+
 		"Accepts binary format (e.g. 1.5Ki, 1Mi, 3.6Gi). ")
+
+	fs.IntVar(&cfg.MaxConnections, "max-connections", cfg.MaxConnections, "<count>"+
+		"Maximum number of simultaneous client connections. "+
+		"When the limit is reached, new connections wait until an existing connection is closed. "+
+		"Zero means no limit. ")
 }
 
 func DenyDomains(fs *pflag.FlagSet, cfg *[]ruleset.RegexpListItem) {
@@ -152,6 +165,17 @@ func DirectDomains(fs *pflag.FlagSet, cfg *[]ruleset.RegexpListItem) {
 			"This flag takes precedence over the PAC script.")
 }
 
+func NoProxy(fs *pflag.FlagSet, cfg *[]string) {
+	fs.StringSliceVar(cfg,
+		"no-proxy", *cfg, "<host[:port]|.domain[:port]|cidr|*>,..."+
+			"Connect directly to the specified hosts without using the upstream proxy. "+
+			"Use a domain name to match the domain and its subdomains, "+
+			"a domain prefixed with '.' or '*.' to match subdomains only, "+
+			"an IP address or CIDR range to match IPs, "+
+			"and '*' to match all hosts. "+
+			"Add a port to a host or IP address to match only that port, for example example.com:8080 or [::1]:8080. ")
+}
+
 func MITMConfig(fs *pflag.FlagSet, mitm *bool, cfg *forwarder.MITMConfig) {
 	fs.BoolVar(mitm, "mitm", *mitm, ""+
 		"Enable Man-in-the-Middle (MITM) mode. "+
@@ -186,12 +210,23 @@ func MITMDomains(fs *pflag.FlagSet, cfg *[]ruleset.RegexpListItem) {
 
 func Credentials(fs *pflag.FlagSet, credentials *[]*forwarder.HostPortUser) {
 	fs.VarP(anyflag.NewSliceValueWithRedact[*forwarder.HostPortUser](*credentials, credentials, forwarder.ParseHostPortUser, forwarder.RedactHostPortUser),
-		"credentials", "s", "<username[:password]@host:port,...>"+
+		"credentials", "s", "<username[:password]@host[:port],...>"+
 			"Site or upstream proxy basic authentication credentials. "+
 			"The host and port can be set to \"*\" to match all hosts and ports respectively. "+
+			"If the port is omitted, the credentials match all ports of the host, "+
+			"credentials with an explicit port take precedence. "+
+			"The host can be set to \"*.<domain>\" to match all single label subdomains of the domain, it does not match IP addresses. "+
+			"If multiple credentials match, the first of the following wins: host and port, host with any port, "+
+			"subdomain wildcard and port, subdomain wildcard with any port, any host and port, any host with any port. "+
 			"The flag can be specified multiple times to add multiple credentials. ")
 }
 
+func AllowInsecureCredentials(fs *pflag.FlagSet, allow *bool) {
+	fs.BoolVar(allow, "allow-insecure-credentials", *allow,
+		"Don't warn when site credentials are added to plain HTTP requests. "+
+			"By default, the proxy logs a warning the first time each credential is sent unencrypted. ")
+}
+
 func HTTPTransportConfig(fs *pflag.FlagSet, cfg *forwarder.HTTPTransportConfig) {
 	DialConfig(fs, &cfg.DialConfig, "http")
 
@@ -236,6 +271,16 @@ func TLSClientConfig(fs *pflag.FlagSet, cfg *forwarder.TLSClientConfig) {
 			"The system root certificates will be used in addition to any certificates in this list. "+
 			"Can be a path to a file or \"data:\" followed by base64 encoded certificate. "+
 			"Use this flag multiple times to specify multiple CA certificate files. ")
+
+	fs.Var(anyflag.NewValueWithRedact[string](cfg.CertFile, &cfg.CertFile, func(val string) (string, error) { return val, nil }, RedactBase64),
+		"client-cert-file", "<path or base64>"+
+			"TLS client certificate to present to servers and upstream proxies that require mutual TLS. "+
+			"Can be a path to a file or \"data:\" followed by base64 encoded certificate. ")
+
+	fs.Var(anyflag.NewValueWithRedact[string](cfg.KeyFile, &cfg.KeyFile, func(val string) (string, error) { return val, nil }, RedactBase64),
+		"client-key-file", "<path or base64>"+
+			"TLS private key of the client certificate. "+
+			"Can be a path to a file or \"data:\" followed by base64 encoded key. ")
 }
 
 func HTTPServerConfig(fs *pflag.FlagSet, cfg *forwarder.HTTPServerConfig, prefix string, schemes ...forwarder.Scheme) {
@@ -50,6 +50,7 @@ func FlagGroups() templates.FlagGroups {
 				"pac",
 
 				"direct-domains",
+				"no-proxy",
 				"deny-domains",
 
 				"header",
@@ -71,6 +72,8 @@ func FlagGroups() templates.FlagGroups {
 			Prefix: []string{
 				"http",
 				"cacert-file",
+				"client-cert-file",
+				"client-key-file",
 				"insecure",
 			},
 		},
@@ -25,7 +25,10 @@ type command struct {
 }
 
 func (c *command) runE(cmd *cobra.Command, args []string) error {
-	if len(c.dnsConfig.Servers) > 0 {
+	if len(c.dnsConfig.Servers) > 0 || len(c.dnsConfig.Search) > 0 {
+		if dups := c.dnsConfig.RemoveDuplicateServers(); len(dups) > 0 {
+			fmt.Fprintf(cmd.ErrOrStderr(), "ignoring duplicate DNS servers %v\n", dups)
+		}
 		if err := osdns.Configure(c.dnsConfig); err != nil {
 			return fmt.Errorf("configure DNS: %w", err)
 		}
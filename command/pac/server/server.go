@@ -68,9 +68,17 @@ func (c *command) runE(cmd *cobra.Command, _ []string) (cmdErr error) {
 		logger.Debugf("all configuration\n%s\n\n", cfg)
 	}
 
-	if len(c.dnsConfig.Servers) > 0 {
-		s := strings.ReplaceAll(fmt.Sprintf("%s", c.dnsConfig.Servers), " ", ", ")
-		logger.Named("dns").Infof("using DNS servers %v", s)
+	if len(c.dnsConfig.Servers) > 0 || len(c.dnsConfig.Search) > 0 {
+		if dups := c.dnsConfig.RemoveDuplicateServers(); len(dups) > 0 {
+			logger.Named("dns").Infof("ignoring duplicate DNS servers %v", dups)
+		}
+		if len(c.dnsConfig.Servers) > 0 {
+			s := strings.ReplaceAll(fmt.Sprintf("%s", c.dnsConfig.Servers), " ", ", ")
+			logger.Named("dns").Infof("using DNS servers %v", s)
+		}
+		if len(c.dnsConfig.Search) > 0 {
+			logger.Named("dns").Infof("using DNS search domains %s", strings.Join(c.dnsConfig.Search, ", "))
+		}
 		if err := osdns.Configure(c.dnsConfig); err != nil {
 			return fmt.Errorf("configure DNS: %w", err)
 		}
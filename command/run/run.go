@@ -123,9 +123,17 @@ func (c *command) runE(cmd *cobra.Command, _ []string) (cmdErr error) {
 
 	martianlog.SetLogger(logger.Named("proxy"))
 
-	if len(c.dnsConfig.Servers) > 0 {
-		s := strings.ReplaceAll(fmt.Sprintf("%s", c.dnsConfig.Servers), " ", ", ")
-		logger.Named("dns").Infof("using DNS servers %v", s)
+	if len(c.dnsConfig.Servers) > 0 || len(c.dnsConfig.Search) > 0 {
+		if dups := c.dnsConfig.RemoveDuplicateServers(); len(dups) > 0 {
+			logger.Named("dns").Infof("ignoring duplicate DNS servers %v", dups)
+		}
+		if len(c.dnsConfig.Servers) > 0 {
+			s := strings.ReplaceAll(fmt.Sprintf("%s", c.dnsConfig.Servers), " ", ", ")
+			logger.Named("dns").Infof("using DNS servers %v", s)
+		}
+		if len(c.dnsConfig.Search) > 0 {
+			logger.Named("dns").Infof("using DNS search domains %s", strings.Join(c.dnsConfig.Search, ", "))
+		}
 		if err := osdns.Configure(c.dnsConfig); err != nil {
 			return fmt.Errorf("configure dns: %w", err)
 		}
@@ -413,8 +421,10 @@ func Command() *cobra.Command {
 	bind.HTTPTransportConfig(fs, c.httpTransportConfig)
 	bind.PAC(fs, &c.pac)
 	bind.Credentials(fs, &c.credentials)
+	bind.AllowInsecureCredentials(fs, &c.httpProxyConfig.AllowInsecureCredentials)
 	bind.DenyDomains(fs, &c.denyDomains)
 	bind.DirectDomains(fs, &c.directDomains)
+	bind.NoProxy(fs, &c.httpProxyConfig.NoProxy)
 	bind.ConnectHeaders(fs, &c.connectHeaders)
 	bind.RequestHeaders(fs, &c.requestHeaders)
 	bind.ResponseHeaders(fs, &c.responseHeaders)
@@ -24,15 +24,50 @@ import (
 
 // ParseUserinfo parses a user:password string into *url.Userinfo.
 func ParseUserinfo(val string) (*url.Userinfo, error) {
+	return parseUserinfo(val, func(s string) string { return s })
+}
+
+// parseUserinfo splits val into username and password and calls expand on each of them.
+func parseUserinfo(val string, expand func(string) string) (*url.Userinfo, error) {
 	if val == "" {
 		return nil, errors.New("expected username[:password]")
 	}
 
 	var ui *url.Userinfo
 	u, p, ok := strings.Cut(val, ":")
+	if !ok {
+		ui = url.User(expand(u))
+	} else {
+		ui = url.UserPassword(expand(u), expand(p))
+	}
+	if err := validatedUserInfo(ui); err != nil {
+		return nil, err
+	}
+
+	return ui, nil
+}
+
+// ParseUserinfoEncoded is like ParseUserinfo but the username and password are percent-encoded,
+// this allows to use ':' in the username, for example "us%3Aer:p%40ss" is parsed as user "us:er" and password "p@ss".
+func ParseUserinfoEncoded(val string) (*url.Userinfo, error) {
+	if val == "" {
+		return nil, errors.New("expected username[:password]")
+	}
+
+	u, p, ok := strings.Cut(val, ":")
+	u, err := url.PathUnescape(u)
+	if err != nil {
+		return nil, fmt.Errorf("username: %w", err)
+	}
+
+	var ui *url.Userinfo
 	if !ok {
 		ui = url.User(u)
 	} else {
+		p, err := url.PathUnescape(p)
+		if err != nil {
+			return nil, fmt.Errorf("password: %w", err)
+		}
 		ui = url.UserPassword(u, p)
 	}
 	if err := validatedUserInfo(ui); err != nil {
@@ -61,10 +96,11 @@ func wildcardPortTo0(val string) string {
 	return strings.Join(s, ":")
 }
 
-// ParseHostPortUser parses a user:password@host:port string into HostUser.
+// ParseHostPortUser parses a user:password@host[:port] string into HostUser.
+// If the port is not specified, the credentials match any port.
 func ParseHostPortUser(val string) (*HostPortUser, error) {
 	if val == "" || !strings.Contains(val, "@") {
-		return nil, errors.New("expected user[:password]@host:port")
+		return nil, errors.New("expected user[:password]@host[:port]")
 	}
 
 	idx := strings.LastIndex(val, "@")
@@ -95,11 +131,51 @@ func ParseHostPortUser(val string) (*HostPortUser, error) {
 }
 
 func ParseProxyURL(val string) (*url.URL, error) {
+	return parseProxyURL(val, nil, false)
+}
+
+// ParseProxyURLWithDefaults is like ParseProxyURL but if port is not specified,
+// it uses the default port for the scheme i.e. 80 for http, 443 for https and 1080 for socks5.
+func ParseProxyURLWithDefaults(val string) (*url.URL, error) {
+	return parseProxyURL(val, nil, true)
+}
+
+var defaultProxyPorts = map[string]string{
+	"http":   "80",
+	"https":  "443",
+	"socks5": "1080",
+}
+
+// ParseProxyURLExpand is like ParseProxyURL but it replaces ${var} or $var in the URL
+// according to the mapping function, see os.Expand for details.
+// If val has no user info, it is expanded as a whole before parsing, so a variable may hold the whole URL.
+// Otherwise, val is split into the user info and host on the unexpanded '@',
+// and the user info is split on the unexpanded ':'.
+// The username, password, scheme and host are then expanded separately,
+// so that the expanded username and password may contain special characters like '@' or ':'.
+// Undefined variables should be mapped to an empty string by the mapping function,
+// this fails validation if the value is required e.g. username or host.
+func ParseProxyURLExpand(val string, mapping func(string) string) (*url.URL, error) {
+	expand := func(s string) string {
+		return os.Expand(s, mapping)
+	}
+	if !strings.Contains(val, "@") {
+		return parseProxyURL(expand(val), nil, false)
+	}
+	return parseProxyURL(val, expand, false)
+}
+
+func parseProxyURL(val string, expand func(string) string, defaultPort bool) (*url.URL, error) {
+	if expand == nil {
+		expand = func(s string) string { return s }
+	}
+
 	scheme, hpu, ok := strings.Cut(val, "://")
 	if !ok {
 		scheme = "http"
 		hpu = val
 	}
+	scheme = expand(scheme)
 
 	if strings.Index(hpu, "@") != strings.LastIndex(hpu, "@") {
 		return nil, errors.New("only one '@' is allowed")
@@ -111,19 +187,25 @@ func ParseProxyURL(val string) (*url.URL, error) {
 	)
 	up, hp, ok := strings.Cut(hpu, "@")
 	if ok {
-		ui, err = ParseUserinfo(up)
+		ui, err = parseUserinfo(up, expand)
 		if err != nil {
 			return nil, err
 		}
 	} else {
 		hp = hpu
 	}
+	hp = unescapeZone(expand(hp))
 
 	u := &url.URL{
 		Scheme: scheme,
 		Host:   hp,
 		User:   ui,
 	}
+	if defaultPort && u.Port() == "" {
+		if p, ok := defaultProxyPorts[u.Scheme]; ok {
+			u.Host = net.JoinHostPort(u.Hostname(), p)
+		}
+	}
 	if err := validateProxyURL(u); err != nil {
 		return nil, err
 	}
@@ -131,19 +213,26 @@ func ParseProxyURL(val string) (*url.URL, error) {
 	return u, nil
 }
 
+// SupportedProxySchemes is a list of upstream proxy URL schemes supported by ParseProxyURL.
+var SupportedProxySchemes = []string{
+	"http",
+	"https",
+	"socks5",
+}
+
+// IsSupportedProxyScheme returns true if scheme is one of SupportedProxySchemes.
+func IsSupportedProxyScheme(scheme string) bool {
+	return slices.Contains(SupportedProxySchemes, scheme)
+}
+
 func validateProxyURL(u *url.URL) error {
 	if u == nil {
 		return nil
 	}
 
 	{
-		supportedSchemes := []string{
-			"http",
-			"https",
-			"socks5",
-		}
-		if !slices.Contains(supportedSchemes, u.Scheme) {
-			return fmt.Errorf("unsupported scheme %q, supported schemes are: %s", u.Scheme, strings.Join(supportedSchemes, ", "))
+		if !IsSupportedProxyScheme(u.Scheme) {
+			return fmt.Errorf("unsupported scheme %q, supported schemes are: %s", u.Scheme, strings.Join(SupportedProxySchemes, ", "))
 		}
 	}
 
@@ -151,8 +240,13 @@ func validateProxyURL(u *url.URL) error {
 		if err := validatedUserInfo(u.User); err != nil {
 			return err
 		}
+		if u.Scheme == "socks5" {
+			if err := validateSOCKS5UserInfo(u.User); err != nil {
+				return err
+			}
+		}
 
-		c, err := url.Parse(fmt.Sprintf("%s://%s", u.Scheme, u.Host))
+		c, err := url.Parse(fmt.Sprintf("%s://%s", u.Scheme, escapeZone(u.Host)))
 		if err != nil {
 			return err
 		}
@@ -194,12 +288,48 @@ func validateProxyURL(u *url.URL) error {
 	return nil
 }
 
+// escapeZone escapes the IPv6 zone separator in a bracketed host, so that it can be parsed by url.Parse.
+func escapeZone(hp string) string {
+	if !strings.HasPrefix(hp, "[") {
+		return hp
+	}
+	return strings.Replace(hp, "%", "%25", 1)
+}
+
+// unescapeZone is the inverse of escapeZone, it allows users to specify the zone either as %zone or %25zone.
+func unescapeZone(hp string) string {
+	if !strings.HasPrefix(hp, "[") {
+		return hp
+	}
+	return strings.Replace(hp, "%25", "%", 1)
+}
+
+// socks5MaxCredentialLength is the maximum length of username and password as specified in RFC 1929.
+const socks5MaxCredentialLength = 255
+
+func validateSOCKS5UserInfo(ui *url.Userinfo) error {
+	if ui == nil {
+		return nil
+	}
+	if len(ui.Username()) > socks5MaxCredentialLength {
+		return fmt.Errorf("username cannot be longer than %d bytes for socks5", socks5MaxCredentialLength)
+	}
+	if p, _ := ui.Password(); len(p) > socks5MaxCredentialLength {
+		return fmt.Errorf("password cannot be longer than %d bytes for socks5", socks5MaxCredentialLength)
+	}
+	return nil
+}
+
 func ParseDNSAddress(val string) (netip.AddrPort, error) {
 	var empty netip.AddrPort
 
 	host, port, _ := net.SplitHostPort(val)
 	if host == "" {
 		host = val
+		// Handle IPv6 address in brackets without port.
+		if h, ok := strings.CutPrefix(host, "["); ok {
+			host = strings.TrimSuffix(h, "]")
+		}
 	}
 
 	a, err := netip.ParseAddr(host)
@@ -236,6 +366,18 @@ func validateDNSAddress(p netip.AddrPort) error {
 	return nil
 }
 
+// ParseDNSSearchDomain parses a domain name to be used as DNS search domain.
+// The trailing dot is optional.
+func ParseDNSSearchDomain(val string) (string, error) {
+	if strings.ContainsAny(val, "/:") {
+		return "", fmt.Errorf("invalid search domain %q, expected domain name without scheme or port", val)
+	}
+	if !isDomainName(val) {
+		return "", fmt.Errorf("invalid search domain %q", val)
+	}
+	return val, nil
+}
+
 //go:linkname isDomainName net.isDomainName
 func isDomainName(s string) bool
 
@@ -11,6 +11,7 @@ import (
 	"fmt"
 	"net"
 	"net/url"
+	"strings"
 
 	"github.com/saucelabs/forwarder/log"
 )
@@ -25,21 +26,31 @@ func (hpu *HostPortUser) Validate() error {
 	if hpu.Host == "" {
 		return errors.New("missing host")
 	}
-	if hpu.Port == "" {
-		return errors.New("missing port")
-	}
 	if hpu.Userinfo == nil {
 		return errors.New("missing user")
 	}
+	if strings.Contains(hpu.Host, "*") && hpu.Host != "*" {
+		if d, ok := strings.CutPrefix(hpu.Host, "*."); !ok || !isDomainName(d) {
+			return fmt.Errorf("invalid wildcard host %s, expected *.<domain>", hpu.Host)
+		}
+	}
 	return validatedUserInfo(hpu.Userinfo)
 }
 
+// port returns the port or "0" if the port is not set, which matches any port.
+func (hpu *HostPortUser) port() string {
+	if hpu.Port == "" {
+		return "0"
+	}
+	return hpu.Port
+}
+
 func (hpu *HostPortUser) String() string {
 	if hpu == nil {
 		return ""
 	}
 
-	port := hpu.Port
+	port := hpu.port()
 	if port == "0" {
 		port = "*"
 	}
@@ -57,7 +68,7 @@ func RedactHostPortUser(hpu *HostPortUser) string {
 		return ""
 	}
 
-	port := hpu.Port
+	port := hpu.port()
 	if port == "0" {
 		port = "*"
 	}
@@ -77,6 +88,9 @@ type CredentialsMatcher struct {
 	log      log.Logger
 }
 
+// NewCredentialsMatcher returns a matcher for the given credentials.
+// Duplicate entries for the same host and port are allowed if they specify the same user info,
+// otherwise an error listing the conflicting entries is returned.
 func NewCredentialsMatcher(credentials []*HostPortUser, log log.Logger) (*CredentialsMatcher, error) {
 	if len(credentials) == 0 {
 		return nil, nil //nolint:nilnil // nil is a valid value
@@ -89,36 +103,32 @@ func NewCredentialsMatcher(credentials []*HostPortUser, log log.Logger) (*Creden
 		log:      log,
 	}
 
+	seen := make(map[string]int, len(credentials))
 	for i, hpu := range credentials {
-		withRowInfo := func(err error) error {
-			return fmt.Errorf("%w at pos %d", err, i) //nolint:scopelint // false positive
+		if err := hpu.Validate(); err != nil {
+			return nil, fmt.Errorf("%w at pos %d", err, i)
 		}
 
-		if err := hpu.Validate(); err != nil {
-			return nil, withRowInfo(err)
+		port := hpu.port()
+		hostport := net.JoinHostPort(hpu.Host, port)
+		if j, ok := seen[hostport]; ok {
+			if credentials[j].Userinfo.String() == hpu.Userinfo.String() {
+				log.Debugf("ignoring duplicate credentials %s at pos %d", RedactHostPortUser(hpu), i)
+				continue
+			}
+			return nil, fmt.Errorf("conflicting credentials %s at pos %d and %s at pos %d",
+				RedactHostPortUser(credentials[j]), j, RedactHostPortUser(hpu), i)
 		}
+		seen[hostport] = i
 
 		switch {
-		case hpu.Host == "*" && hpu.Port == "0":
-			if m.global != nil {
-				return nil, withRowInfo(errors.New("duplicate global input"))
-			}
+		case hpu.Host == "*" && port == "0":
 			m.global = hpu.Userinfo
 		case hpu.Host == "*":
-			if _, ok := m.port[hpu.Port]; ok {
-				return nil, withRowInfo(fmt.Errorf("duplicate wildcard host with port %s credentis", hpu.Port))
-			}
-			m.port[hpu.Port] = hpu.Userinfo
-		case hpu.Port == "0":
-			if _, ok := m.host[hpu.Host]; ok {
-				return nil, withRowInfo(fmt.Errorf("duplicate wildcard port with host %s credentis", hpu.Host))
-			}
+			m.port[port] = hpu.Userinfo
+		case port == "0":
 			m.host[hpu.Host] = hpu.Userinfo
 		default:
-			hostport := net.JoinHostPort(hpu.Host, hpu.Port)
-			if _, ok := m.hostport[hostport]; ok {
-				return nil, errors.New("duplicate input")
-			}
 			m.hostport[hostport] = hpu.Userinfo
 		}
 	}
@@ -154,7 +164,10 @@ func (m *CredentialsMatcher) MatchURL(u *url.URL) *url.Userinfo {
 }
 
 // Match `hostport` to one of the configured input.
-// Priority is exact Match, then host, then port, then global wildcard.
+// Priority is exact Match, then host with any port, then subdomain wildcard with port,
+// then subdomain wildcard with any port, then any host with port, then global wildcard.
+// Subdomain wildcard i.e. *.example.com matches single label subdomains of example.com, it never matches IP addresses.
+// Note that host with any port takes precedence over any host with port i.e. abc:* wins over *:443 for abc:443.
 func (m *CredentialsMatcher) Match(hostport string) *url.Userinfo {
 	if m == nil {
 		return nil
@@ -171,18 +184,40 @@ func (m *CredentialsMatcher) Match(hostport string) *url.Userinfo {
 		return nil
 	}
 
-	// Host wildcard - check the port only.
-	if u, ok := m.port[port]; ok {
-		m.log.Debugf("host=* port=%s", port)
-		return u
-	}
-
 	// Port wildcard - check the host only.
 	if u, ok := m.host[host]; ok {
 		m.log.Debugf("host=%s port=*", host)
 		return u
 	}
 
+	// Subdomain wildcards match domain names only, IP addresses are skipped.
+	var wildcardHost string
+	if net.ParseIP(host) == nil {
+		if _, parent, ok := strings.Cut(host, "."); ok && parent != "" {
+			wildcardHost = "*." + parent
+		}
+	}
+
+	if wildcardHost != "" {
+		// Subdomain wildcard - check the parent domain and the port.
+		if u, ok := m.hostport[net.JoinHostPort(wildcardHost, port)]; ok {
+			m.log.Debugf("host=%s port=%s", wildcardHost, port)
+			return u
+		}
+
+		// Subdomain and port wildcard - check the parent domain only.
+		if u, ok := m.host[wildcardHost]; ok {
+			m.log.Debugf("host=%s port=*", wildcardHost)
+			return u
+		}
+	}
+
+	// Host wildcard - check the port only.
+	if u, ok := m.port[port]; ok {
+		m.log.Debugf("host=* port=%s", port)
+		return u
+	}
+
 	// Log whether the global wildcard is set.
 	// This is a very esoteric use case. It's only added to support a legacy implementation.
 	if m.global != nil {
@@ -54,6 +54,82 @@ func TestUserInfoMatcherMatch(t *testing.T) {
 			hostport: "abc:80",
 			expected: url.UserPassword("user", "pass"),
 		},
+		{
+			name:     "Matches host without port",
+			input:    []string{"user:pass@abc", "baz:pass@*:0"},
+			hostport: "abc:8443",
+			expected: url.UserPassword("user", "pass"),
+		},
+		{
+			name:     "Exact port takes precedence over host without port",
+			input:    []string{"user:pass@abc", "foo:pass@abc:8080"},
+			hostport: "abc:8080",
+			expected: url.UserPassword("foo", "pass"),
+		},
+		{
+			name:     "Host without port falls back for other ports",
+			input:    []string{"user:pass@abc", "foo:pass@abc:8080"},
+			hostport: "abc:9090",
+			expected: url.UserPassword("user", "pass"),
+		},
+		{
+			name:     "Matches subdomain wildcard",
+			input:    []string{"user:pass@*.corp.example.com:443", "baz:pass@*:0"},
+			hostport: "foo.corp.example.com:443",
+			expected: url.UserPassword("user", "pass"),
+		},
+		{
+			name:     "Subdomain wildcard matches single label only",
+			input:    []string{"user:pass@*.corp.example.com:443"},
+			hostport: "foo.bar.corp.example.com:443",
+		},
+		{
+			name:     "Subdomain wildcard does not match parent domain",
+			input:    []string{"user:pass@*.corp.example.com:443"},
+			hostport: "corp.example.com:443",
+		},
+		{
+			name:     "Exact host takes precedence over subdomain wildcard",
+			input:    []string{"user:pass@*.corp.example.com:443", "foo:pass@foo.corp.example.com:443"},
+			hostport: "foo.corp.example.com:443",
+			expected: url.UserPassword("foo", "pass"),
+		},
+		{
+			name:     "Exact host with any port takes precedence over subdomain wildcard",
+			input:    []string{"user:pass@*.corp.example.com:443", "foo:pass@foo.corp.example.com:*"},
+			hostport: "foo.corp.example.com:443",
+			expected: url.UserPassword("foo", "pass"),
+		},
+		{
+			name:     "Exact host without port takes precedence over subdomain wildcard",
+			input:    []string{"user:pass@*.corp.example.com:443", "foo:pass@foo.corp.example.com"},
+			hostport: "foo.corp.example.com:443",
+			expected: url.UserPassword("foo", "pass"),
+		},
+		{
+			name:     "Exact host with any port takes precedence over host wildcard",
+			input:    []string{"baz:pass@*:443", "foo:pass@abc:*"},
+			hostport: "abc:443",
+			expected: url.UserPassword("foo", "pass"),
+		},
+		{
+			name:     "Subdomain wildcard takes precedence over host wildcard",
+			input:    []string{"baz:pass@*:443", "user:pass@*.corp.example.com:*"},
+			hostport: "foo.corp.example.com:443",
+			expected: url.UserPassword("user", "pass"),
+		},
+		{
+			name:     "Subdomain wildcard with port takes precedence over subdomain wildcard with any port",
+			input:    []string{"foo:pass@*.corp.example.com:*", "user:pass@*.corp.example.com:443"},
+			hostport: "foo.corp.example.com:443",
+			expected: url.UserPassword("user", "pass"),
+		},
+		{
+			name:     "Matches subdomain and port wildcard",
+			input:    []string{"user:pass@*.corp.example.com:*", "baz:pass@*:0"},
+			hostport: "foo.corp.example.com:8080",
+			expected: url.UserPassword("user", "pass"),
+		},
 	}
 
 	for i := range tests {
@@ -82,3 +158,100 @@ func TestUserInfoMatcherMatch(t *testing.T) {
 		})
 	}
 }
+
+func TestCredentialsMatcherSubdomainWildcardIP(t *testing.T) {
+	// Validation rejects numeric wildcard domains, the matcher is built directly to check that IPs are not looked up.
+	u := url.UserPassword("user", "pass")
+	m := &CredentialsMatcher{
+		hostport: map[string]*url.Userinfo{"*.0.0.1:80": u},
+		host:     map[string]*url.Userinfo{"*.0.0.1": u},
+		port:     map[string]*url.Userinfo{},
+		log:      stdlog.Default(),
+	}
+	if got := m.Match("127.0.0.1:80"); got != nil {
+		t.Fatalf("expected no match, got %s", got)
+	}
+}
+
+func TestHostPortUserValidateWildcard(t *testing.T) {
+	tests := []struct {
+		input string
+		valid bool
+	}{
+		{"user:pass@*:80", true},
+		{"user:pass@abc", true},
+		{"user:pass@*.example.com", true},
+		{"user:pass@*.example.com:80", true},
+		{"user:pass@foo.*.com:80", false},
+		{"user:pass@*example.com:80", false},
+		{"user:pass@*.:80", false},
+	}
+
+	for i := range tests {
+		tc := tests[i]
+		_, err := ParseHostPortUser(tc.input)
+		if tc.valid && err != nil {
+			t.Errorf("ParseHostPortUser(%q): unexpected error %s", tc.input, err)
+		}
+		if !tc.valid && err == nil {
+			t.Errorf("ParseHostPortUser(%q): expected error", tc.input)
+		}
+	}
+}
+
+func TestNewCredentialsMatcherDuplicates(t *testing.T) {
+	tests := []struct {
+		name  string
+		input []string
+		err   string
+	}{
+		{
+			name:  "exact duplicate",
+			input: []string{"user:pass@abc:80", "user:pass@abc:80"},
+		},
+		{
+			name:  "exact duplicate wildcard port",
+			input: []string{"user:pass@abc:*", "user:pass@abc:0"},
+		},
+		{
+			name:  "conflicting wildcard port and no port",
+			input: []string{"user:pass@abc:*", "user:other@abc"},
+			err:   "conflicting credentials user:xxxxx@abc:* at pos 0 and user:xxxxx@abc:* at pos 1",
+		},
+		{
+			name:  "conflicting hostport",
+			input: []string{"user:pass@abc:80", "foo:pass@xyz:80", "user:other@abc:80"},
+			err:   "conflicting credentials user:xxxxx@abc:80 at pos 0 and user:xxxxx@abc:80 at pos 2",
+		},
+		{
+			name:  "conflicting global",
+			input: []string{"user:pass@*:*", "foo:pass@*:*"},
+			err:   "conflicting credentials user:xxxxx@*:* at pos 0 and foo:xxxxx@*:* at pos 1",
+		},
+	}
+
+	for i := range tests {
+		tc := tests[i]
+		t.Run(tc.name, func(t *testing.T) {
+			credentials := make([]*HostPortUser, len(tc.input))
+			for i := range tc.input {
+				var err error
+				credentials[i], err = ParseHostPortUser(tc.input[i])
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			_, err := NewCredentialsMatcher(credentials, stdlog.Default())
+			if tc.err == "" {
+				if err != nil {
+					t.Fatalf("expected success, got %q", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tc.err {
+				t.Fatalf("expected error %q, got %v", tc.err, err)
+			}
+		})
+	}
+}
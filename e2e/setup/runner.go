@@ -30,6 +30,9 @@ type Runner struct {
 	Debug         bool
 	Parallel      int
 
+	// CommandRunner replaces the compose command runner, it is used in tests.
+	CommandRunner compose.Runner
+
 	td errgroup.Group
 	mu sync.Mutex
 }
@@ -78,7 +81,7 @@ func (r *Runner) Run(ctx context.Context) error {
 			if ctx.Err() != nil {
 				return ctx.Err()
 			}
-			if err := r.runSetup(s); err != nil {
+			if err := r.runSetup(ctx, s); err != nil {
 				return fmt.Errorf("setup %s: %w", s.Name, err)
 			}
 			return nil
@@ -91,7 +94,7 @@ func (r *Runner) Run(ctx context.Context) error {
 	return g.Wait()
 }
 
-func (r *Runner) runSetup(s *Setup) (runErr error) {
+func (r *Runner) runSetup(ctx context.Context, s *Setup) (runErr error) {
 	if s.Compose.Services[TestServiceName] == nil {
 		return fmt.Errorf("missing %s service", TestServiceName)
 	}
@@ -112,6 +115,9 @@ func (r *Runner) runSetup(s *Setup) (runErr error) {
 	if err != nil {
 		return err
 	}
+	if r.CommandRunner != nil {
+		cmd.Runner = r.CommandRunner
+	}
 
 	if !r.Debug {
 		defer func() {
@@ -175,7 +181,7 @@ func (r *Runner) runSetup(s *Setup) (runErr error) {
 	if r.OnComposeUp != nil {
 		r.OnComposeUp(s)
 	}
-	if err := cmd.Up(args...); err != nil {
+	if err := cmd.UpContext(ctx, args...); err != nil {
 		return fmt.Errorf("compose up: %w", err)
 	}
 
@@ -184,12 +190,12 @@ func (r *Runner) runSetup(s *Setup) (runErr error) {
 	if CI {
 		waitTimeout = 60 * time.Second
 	}
-	if err := cmd.Wait(time.Second, waitTimeout, r.services(s)); err != nil {
+	if err := cmd.WaitContext(ctx, time.Second, waitTimeout, r.services(s)); err != nil {
 		return fmt.Errorf("wait for services: %w", err)
 	}
 
 	// Run the test service.
-	return cmd.Up("--force-recreate", "--exit-code-from", TestServiceName, TestServiceName)
+	return cmd.UpContext(ctx, "--force-recreate", "--exit-code-from", TestServiceName, TestServiceName)
 }
 
 func (r *Runner) services(s *Setup) []string {
@@ -0,0 +1,140 @@
+// Copyright 2022-2024 Sauce Labs Inc., all rights reserved.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package setup
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/saucelabs/forwarder/utils/compose"
+)
+
+// fakeRunner records the compose subcommands and reports all services as healthy.
+type fakeRunner struct {
+	mu     sync.Mutex
+	events *[]string
+}
+
+func (r *fakeRunner) Run(cmd *exec.Cmd) error {
+	switch cmd.Args[1] {
+	case "inspect":
+		io.WriteString(cmd.Stdout, "healthy")
+		r.record("inspect")
+	case "compose":
+		r.record("compose " + cmd.Args[2])
+	}
+	return nil
+}
+
+func (r *fakeRunner) record(e string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// Wait polls the health of the services, record it only once.
+	if e == "inspect" && len(*r.events) > 0 && (*r.events)[len(*r.events)-1] == e {
+		return
+	}
+	*r.events = append(*r.events, e)
+}
+
+func testSetup(t *testing.T) Setup {
+	t.Helper()
+
+	c, err := compose.NewBuilder().
+		AddService(&compose.Service{Name: "proxy", Image: "proxy"}).
+		AddService(&compose.Service{Name: TestServiceName, Image: "test"}).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Setup{Name: "test", Compose: c}
+}
+
+// chdir is like testing.T.Chdir, which is not available in Go 1.21.
+// Tests using it must not run in parallel, as the working directory is process-wide.
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := os.Chdir(wd); err != nil {
+			t.Errorf("restore working directory: %v", err)
+		}
+	})
+}
+
+func TestRunnerRun(t *testing.T) {
+	t.Setenv("CONTAINER_RUNTIME", "docker")
+	t.Setenv("COMPOSE_BINARY", "")
+	t.Setenv("COMPOSE_PROJECT_NAME", "")
+
+	ci := CI
+	CI = false
+	t.Cleanup(func() { CI = ci })
+
+	tests := []struct {
+		name   string
+		debug  bool
+		events []string
+	}{
+		{
+			name: "teardown",
+			events: []string{
+				"on up", "compose up", "inspect", "compose up",
+				"compose down", "on down",
+			},
+		},
+		{
+			name:  "debug preserves containers",
+			debug: true,
+			events: []string{
+				"on up", "compose up", "inspect", "compose up",
+			},
+		},
+	}
+
+	for i := range tests {
+		tc := &tests[i]
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.debug {
+				// Debug mode writes the compose file to the working directory.
+				chdir(t, t.TempDir())
+			}
+
+			var events []string
+			fr := &fakeRunner{events: &events}
+			r := Runner{
+				Setups:        []Setup{testSetup(t)},
+				Debug:         tc.debug,
+				CommandRunner: fr,
+				OnComposeUp: func(*Setup) {
+					fr.record("on up")
+				},
+				OnComposeDown: func(*Setup) {
+					fr.record("on down")
+				},
+			}
+			if err := r.Run(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+
+			if !slices.Equal(events, tc.events) {
+				t.Fatalf("expected %v, got %v", tc.events, events)
+			}
+		})
+	}
+}
@@ -7,20 +7,30 @@
 package fileurl
 
 import (
+	"fmt"
 	"net/url"
+	"os"
+	"os/user"
+	"path"
+	"path/filepath"
 	"regexp"
+	"runtime"
+	"slices"
 	"strings"
 )
 
 var (
 	uncEmptyAuthorityRegex = regexp.MustCompile(`^file:/{4,}([^/])`)
 	windowsVolumeRegex     = regexp.MustCompile(`^/?([a-zA-Z])[:\|]/`)
+	windowsDriveHostRegex  = regexp.MustCompile(`^[a-zA-Z]:$`)
+	schemeRegex            = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9+.-]*:`)
 )
 
 // ParseFilePathOrURL extends url.Parse with the ability to parse file paths
 // and adds extended support for URL file scheme as described in RFC 8089.
 // If there is no scheme, it will be set to "file".
 // If value equals "-", it will be set to "file://-" meaning stdin.
+// File paths are used verbatim, special characters are percent-encoded by URL.String.
 // See: https://datatracker.ietf.org/doc/html/rfc8089
 func ParseFilePathOrURL(val string) (*url.URL, error) {
 	// Handle stdin.
@@ -28,12 +38,37 @@ func ParseFilePathOrURL(val string) (*url.URL, error) {
 		return &url.URL{Scheme: "file", Path: "-"}, nil
 	}
 
+	// Handle Windows extended-length paths, device paths i.e. \\.\pipe\name are handled as UNC paths.
+	if p, ok := strings.CutPrefix(val, `\\?\`); ok {
+		if unc, ok := strings.CutPrefix(p, `UNC\`); ok {
+			val = `\\` + unc
+		} else {
+			val = p
+		}
+	}
+
 	val = strings.ReplaceAll(val, "\\", "/")
 
+	// Handle Windows paths without scheme, they are not parsed to preserve special characters.
+	if windowsVolumeRegex.MatchString(val) {
+		return &url.URL{Scheme: "file", Path: volumePath(val)}, nil
+	}
+
 	// Handle UNC paths.
-	if strings.HasPrefix(val, "//") {
-		val = "file:" + val
+	if unc, ok := strings.CutPrefix(val, "//"); ok {
+		host, p, ok := strings.Cut(unc, "/")
+		if ok {
+			p = "/" + p
+		}
+		return &url.URL{Scheme: "file", Host: host, Path: p}, nil
 	}
+
+	// Handle paths, they are not parsed to preserve special characters i.e. '#', '?' and '%'.
+	// Relative paths omit the host, so that URL.String returns "file:path" that can be parsed back.
+	if !schemeRegex.MatchString(val) {
+		return &url.URL{Scheme: "file", Path: val, OmitHost: isRelative(val)}, nil
+	}
+
 	if m := uncEmptyAuthorityRegex.FindStringSubmatch(val); m != nil {
 		val = "file://" + m[1] + val[len(m[0]):]
 	}
@@ -49,14 +84,160 @@ func ParseFilePathOrURL(val string) (*url.URL, error) {
 		return u, nil
 	}
 
-	// Handle Windows paths.
+	// Handle stdin as returned by URL.String.
+	if u.Host == "-" && u.Path == "" {
+		u.Host, u.Path = "", "-"
+	}
+
+	// Handle Windows and relative paths.
 	if u.Path == "" && u.Opaque != "" {
-		u.Path, u.Opaque = u.Opaque, u.Path
+		p, err := url.PathUnescape(u.Opaque)
+		if err != nil {
+			return nil, err
+		}
+		u.Path, u.Opaque = p, ""
 	}
-	if m := windowsVolumeRegex.FindStringSubmatch(u.Path); m != nil {
-		u.Path = m[1] + ":/" + u.Path[len(m[0]):]
+	// Handle Windows volume parsed as host i.e. file://c:/path/to/file as returned by URL.String.
+	if windowsDriveHostRegex.MatchString(u.Host) {
+		u.Path, u.Host = u.Host+u.Path, ""
 	}
+	u.Path = volumePath(u.Path)
 
-	u.OmitHost = false // include host in the output
+	u.OmitHost = u.Host == "" && isRelative(u.Path) // include host in the output unless the path is relative
 	return u, nil
 }
+
+// volumePath normalizes a Windows volume path to the "c:/path" form, other paths are returned unchanged.
+func volumePath(p string) string {
+	if m := windowsVolumeRegex.FindStringSubmatch(p); m != nil {
+		return m[1] + ":/" + p[len(m[0]):]
+	}
+	return p
+}
+
+func isRelative(p string) bool {
+	return p != "" && p != "-" && !path.IsAbs(p) && !windowsVolumeRegex.MatchString(p)
+}
+
+// ParseFilePathOrURLAllowed is like ParseFilePathOrURL but it returns an error
+// if the URL scheme is not file or one of the allowed schemes.
+func ParseFilePathOrURLAllowed(val string, schemes ...string) (*url.URL, error) {
+	u, err := ParseFilePathOrURL(val)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "file" && !slices.Contains(schemes, u.Scheme) {
+		return nil, fmt.Errorf("unsupported scheme %q, supported schemes are: %s",
+			u.Scheme, strings.Join(append([]string{"file"}, schemes...), ", "))
+	}
+	return u, nil
+}
+
+// ParseFilePathOrURLBase is like ParseFilePathOrURL but it resolves relative file paths against base directory.
+// Absolute paths, stdin and URLs with other schemes are returned unchanged.
+func ParseFilePathOrURLBase(base, val string) (*url.URL, error) {
+	u, err := ParseFilePathOrURL(val)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "file" || u.Host != "" || u.Path == "-" || u.Path == "" {
+		return u, nil
+	}
+	if path.IsAbs(u.Path) || windowsVolumeRegex.MatchString(u.Path) {
+		return u, nil
+	}
+
+	u.Path = filepath.ToSlash(filepath.Join(base, filepath.FromSlash(u.Path)))
+	u.OmitHost = isRelative(u.Path)
+	return u, nil
+}
+
+// ParseFilePathOrURLWithHome is like ParseFilePathOrURL but it expands a leading "~" or "~/" in a path to home,
+// and "~user/" to the home directory of the user.
+// If home is empty, os.UserHomeDir is used.
+// Values with a scheme, including the file scheme, are not expanded.
+func ParseFilePathOrURLWithHome(val, home string) (*url.URL, error) {
+	if strings.HasPrefix(val, "~") {
+		v, err := expandHome(val, home)
+		if err != nil {
+			return nil, err
+		}
+		val = v
+	}
+	return ParseFilePathOrURL(val)
+}
+
+func expandHome(val, home string) (string, error) {
+	name, rest, _ := strings.Cut(strings.ReplaceAll(val[1:], "\\", "/"), "/")
+
+	var dir string
+	if name == "" {
+		dir = home
+		if dir == "" {
+			d, err := os.UserHomeDir()
+			if err != nil {
+				return "", err
+			}
+			dir = d
+		}
+	} else {
+		u, err := user.Lookup(name)
+		if err != nil {
+			return "", err
+		}
+		dir = u.HomeDir
+	}
+
+	if rest == "" {
+		return dir, nil
+	}
+	return filepath.Join(dir, rest), nil
+}
+
+// FileURLToPath is the inverse of ParseFilePathOrURL, it returns an OS path for a file URL.
+// URLs with a host other than localhost are converted to UNC paths i.e. \\host\share\path on Windows,
+// on other systems they result in an error.
+// The stdin URL "file://-" is converted to "-".
+func FileURLToPath(u *url.URL) (string, error) {
+	if u.Scheme != "file" {
+		return "", fmt.Errorf("unsupported scheme %q, expected file", u.Scheme)
+	}
+	if u.Path == "-" {
+		return "-", nil
+	}
+	if u.Path == "" {
+		return "", fmt.Errorf("invalid file URL %q, path is empty", u.String())
+	}
+
+	p := u.Path
+	if windowsVolumeRegex.MatchString(p) {
+		p = strings.TrimPrefix(p, "/")
+	}
+	if u.Host != "" && u.Host != "localhost" {
+		// On POSIX "//host/path" resolves to the local path "/host/path", so a remote file cannot be accessed.
+		if runtime.GOOS != "windows" {
+			return "", fmt.Errorf("invalid file URL %q, remote host %q is supported on Windows only", u.String(), u.Host)
+		}
+		p = "//" + u.Host + p
+	}
+
+	p = filepath.FromSlash(p)
+	if runtime.GOOS == "windows" {
+		p = extendedLengthPath(p)
+	}
+	return p, nil
+}
+
+// maxPath is the maximum length of a Windows path that does not require the \\?\ prefix.
+const maxPath = 260
+
+// extendedLengthPath adds the \\?\ prefix to Windows paths exceeding maxPath.
+func extendedLengthPath(p string) string {
+	if len(p) < maxPath || strings.HasPrefix(p, `\\?\`) || strings.HasPrefix(p, `\\.\`) {
+		return p
+	}
+	if unc, ok := strings.CutPrefix(p, `\\`); ok {
+		return `\\?\UNC\` + unc
+	}
+	return `\\?\` + p
+}
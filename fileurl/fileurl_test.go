@@ -8,6 +8,9 @@ package fileurl
 
 import (
 	"net/url"
+	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -27,11 +30,11 @@ func TestParseFilePathOrURL(t *testing.T) {
 		},
 		{
 			input: "path/to/file",
-			want:  url.URL{Scheme: "file", Path: "path/to/file"},
+			want:  url.URL{Scheme: "file", Path: "path/to/file", OmitHost: true},
 		},
 		{
 			input: "./path/to/file",
-			want:  url.URL{Scheme: "file", Path: "./path/to/file"},
+			want:  url.URL{Scheme: "file", Path: "./path/to/file", OmitHost: true},
 		},
 		{
 			input: "/path/to/file",
@@ -97,6 +100,30 @@ func TestParseFilePathOrURL(t *testing.T) {
 				Path:   "/path/to/file",
 			},
 		},
+		{
+			input: `c:\path\to\file`,
+			want:  url.URL{Scheme: "file", Path: "c:/path/to/file"},
+		},
+		{
+			input: `\\?\C:\very\long\path`,
+			want:  url.URL{Scheme: "file", Path: "C:/very/long/path"},
+		},
+		{
+			input: `\\?\UNC\server\share\path`,
+			want: url.URL{
+				Scheme: "file",
+				Host:   "server",
+				Path:   "/share/path",
+			},
+		},
+		{
+			input: `\\.\pipe\name`,
+			want: url.URL{
+				Scheme: "file",
+				Host:   ".",
+				Path:   "/pipe/name",
+			},
+		},
 		{
 			input: "data:text/plain;base64,U2F1Y2VMYWJzCg==",
 			want: url.URL{
@@ -119,6 +146,213 @@ func TestParseFilePathOrURL(t *testing.T) {
 			if got.EscapedPath() != got.Path {
 				t.Errorf("ParseFilePathOrURL(%q) EscapedPath() = %q, want %q", tc.input, got.EscapedPath(), got.Path)
 			}
+
+			rt, err := ParseFilePathOrURL(got.String())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(got, rt, cmpopts.IgnoreFields(url.URL{}, "RawPath")); diff != "" {
+				t.Errorf("ParseFilePathOrURL(%q) round trip mismatch (-want +got):\n%s", got.String(), diff)
+			}
+		})
+	}
+}
+
+func TestFileURLToPath(t *testing.T) {
+	tests := []struct {
+		input  string
+		want   string
+		remote bool
+	}{
+		{input: "-", want: "-"},
+		{input: "/path/to/file", want: filepath.FromSlash("/path/to/file")},
+		{input: "file:///path/to/file", want: filepath.FromSlash("/path/to/file")},
+		{input: "file://localhost/path/to/file", want: filepath.FromSlash("/path/to/file")},
+		{input: "file:///c:/path/to/file", want: filepath.FromSlash("c:/path/to/file")},
+		{input: "file:///c|/path/to/file", want: filepath.FromSlash("c:/path/to/file")},
+		{input: "file://host.example.com/Share/path/to/file.txt", want: filepath.FromSlash("//host.example.com/Share/path/to/file.txt"), remote: true},
+		{input: `\\host.example.com\Share\file.txt`, want: filepath.FromSlash("//host.example.com/Share/file.txt"), remote: true},
+		{input: "file:///path/to/my%20file", want: filepath.FromSlash("/path/to/my file")},
+	}
+
+	for i := range tests {
+		tc := tests[i]
+		t.Run(tc.input, func(t *testing.T) {
+			u, err := ParseFilePathOrURL(tc.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := FileURLToPath(u)
+			if tc.remote && runtime.GOOS != "windows" {
+				if err == nil || !strings.Contains(err.Error(), "remote host") {
+					t.Fatalf("expected remote host error, got %q, %v", got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Errorf("FileURLToPath(%q) = %q, want %q", tc.input, got, tc.want)
+			}
+		})
+	}
+
+	if _, err := FileURLToPath(&url.URL{Scheme: "http", Host: "example.com", Path: "/file"}); err == nil {
+		t.Error("expected error for http scheme")
+	}
+}
+
+func TestParseFilePathOrURLWithHome(t *testing.T) {
+	home := filepath.FromSlash("/home/me")
+
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{input: "~", want: "/home/me"},
+		{input: "~/", want: "/home/me"},
+		{input: "~/certs/ca.pem", want: "/home/me/certs/ca.pem"},
+		{input: "/path/~/file", want: "/path/~/file"},
+		{input: "file:///~/file", want: "/~/file"},
+		{input: "path/to/file", want: "path/to/file"},
+	}
+
+	for i := range tests {
+		tc := tests[i]
+		t.Run(tc.input, func(t *testing.T) {
+			u, err := ParseFilePathOrURLWithHome(tc.input, home)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if u.Scheme != "file" || u.Path != tc.want {
+				t.Errorf("ParseFilePathOrURLWithHome(%q) = %s, want path %q", tc.input, u, tc.want)
+			}
+		})
+	}
+}
+
+func TestExtendedLengthPath(t *testing.T) {
+	long := strings.Repeat(`\\dir`, 60)
+
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{input: `C:\path\to\file`, want: `C:\path\to\file`},
+		{input: `C:` + long, want: `\\?\C:` + long},
+		{input: `\\server\share` + long, want: `\\?\UNC\server\share` + long},
+		{input: `\\?\C:` + long, want: `\\?\C:` + long},
+		{input: `\\.\pipe` + long, want: `\\.\pipe` + long},
+	}
+
+	for i := range tests {
+		tc := tests[i]
+		if got := extendedLengthPath(tc.input); got != tc.want {
+			t.Errorf("extendedLengthPath(%q) = %q, want %q", tc.input, got, tc.want)
+		}
+	}
+}
+
+func TestParseFilePathOrURLSpecialChars(t *testing.T) {
+	tests := []struct {
+		input string
+		path  string
+		want  string
+	}{
+		{input: "/home/me/My Certs/ca.pem", want: "file:///home/me/My%20Certs/ca.pem"},
+		{input: "/path/#1/file", want: "file:///path/%231/file"},
+		{input: "/path/to/file?.pem", want: "file:///path/to/file%3F.pem"},
+		{input: "/path/100%/file", want: "file:///path/100%25/file"},
+		{input: `\\host\My Share\file`, want: "file://host/My%20Share/file"},
+		{input: `C:\a#b\c.pem`, path: "C:/a#b/c.pem", want: "file://C:/a%23b/c.pem"},
+		{input: `C:\x?y`, path: "C:/x?y", want: "file://C:/x%3Fy"},
+		{input: `C:\100%\file`, path: "C:/100%/file", want: "file://C:/100%25/file"},
+		{input: "./rel#x", path: "./rel#x", want: "file:./rel%23x"},
+		{input: "rel?x", path: "rel?x", want: "file:rel%3Fx"},
+		{input: "../100%/file", path: "../100%/file", want: "file:../100%25/file"},
+	}
+
+	for i := range tests {
+		tc := tests[i]
+		t.Run(tc.input, func(t *testing.T) {
+			u, err := ParseFilePathOrURL(tc.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.path != "" && u.Path != tc.path {
+				t.Errorf("Path = %q, want %q", u.Path, tc.path)
+			}
+			if u.String() != tc.want {
+				t.Errorf("String() = %q, want %q", u.String(), tc.want)
+			}
+
+			ru, err := ParseFilePathOrURL(u.String())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(u, ru, cmpopts.IgnoreFields(url.URL{}, "RawPath")); diff != "" {
+				t.Errorf("round trip mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParseFilePathOrURLAllowed(t *testing.T) {
+	tests := []struct {
+		input string
+		err   string
+	}{
+		{input: "/path/to/file"},
+		{input: "file:///path/to/file"},
+		{input: "https://example.com/file"},
+		{input: "ftp://example.com/file", err: `unsupported scheme "ftp", supported schemes are: file, http, https`},
+		{input: "gopher://example.com/file", err: `unsupported scheme "gopher", supported schemes are: file, http, https`},
+	}
+
+	for i := range tests {
+		tc := tests[i]
+		t.Run(tc.input, func(t *testing.T) {
+			_, err := ParseFilePathOrURLAllowed(tc.input, "http", "https")
+			if tc.err == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tc.err {
+				t.Fatalf("expected error %q, got %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestParseFilePathOrURLBase(t *testing.T) {
+	base := filepath.FromSlash("/etc/forwarder")
+
+	tests := []struct {
+		input string
+		want  url.URL
+	}{
+		{input: "./wpad.dat", want: url.URL{Scheme: "file", Path: "/etc/forwarder/wpad.dat"}},
+		{input: "wpad.dat", want: url.URL{Scheme: "file", Path: "/etc/forwarder/wpad.dat"}},
+		{input: "../wpad.dat", want: url.URL{Scheme: "file", Path: "/etc/wpad.dat"}},
+		{input: "/var/lib/wpad.dat", want: url.URL{Scheme: "file", Path: "/var/lib/wpad.dat"}},
+		{input: "c:/wpad.dat", want: url.URL{Scheme: "file", Path: "c:/wpad.dat"}},
+		{input: "-", want: url.URL{Scheme: "file", Path: "-"}},
+		{input: "https://example.com/wpad.dat", want: url.URL{Scheme: "https", Host: "example.com", Path: "/wpad.dat"}},
+	}
+
+	for i := range tests {
+		tc := tests[i]
+		t.Run(tc.input, func(t *testing.T) {
+			got, err := ParseFilePathOrURLBase(base, tc.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.want, *got, cmpopts.IgnoreFields(url.URL{}, "RawPath")); diff != "" {
+				t.Errorf("ParseFilePathOrURLBase(%q) mismatch (-want +got):\n%s", tc.input, diff)
+			}
 		})
 	}
 }
@@ -86,6 +86,7 @@ type HTTPProxyConfig struct {
 	UpstreamProxyFunc ProxyFunc
 	DenyDomains       Matcher
 	DirectDomains     Matcher
+	NoProxy           []string
 	RequestIDHeader   string
 	RequestModifiers  []RequestModifier
 	ResponseModifiers []ResponseModifier
@@ -93,6 +94,11 @@ type HTTPProxyConfig struct {
 	ConnectTimeout    time.Duration
 	ReadLimit         SizeSuffix
 	WriteLimit        SizeSuffix
+	MaxConnections    int
+
+	// AllowInsecureCredentials disables the warning logged when site credentials
+	// are added to a plain HTTP request, and thus sent unencrypted.
+	AllowInsecureCredentials bool
 
 	// TestingHTTPHandler uses Martian's [http.Handler] implementation
 	// over [http.Server] instead of the default TCP server.
@@ -117,21 +123,30 @@ func DefaultHTTPProxyConfig() *HTTPProxyConfig {
 	}
 }
 
+// Validate checks the configuration and returns all problems found joined with errors.Join.
 func (c *HTTPProxyConfig) Validate() error {
+	var errs []error
+
 	if err := c.HTTPServerConfig.Validate(); err != nil {
-		return err
+		errs = append(errs, err)
 	}
 	if c.Protocol != HTTPScheme && c.Protocol != HTTPSScheme {
-		return fmt.Errorf("unsupported protocol: %s", c.Protocol)
+		errs = append(errs, fmt.Errorf("unsupported protocol: %s", c.Protocol))
 	}
 	if !c.ProxyLocalhost.isValid() {
-		return fmt.Errorf("unsupported proxy_localhost: %s", c.ProxyLocalhost)
+		errs = append(errs, fmt.Errorf("unsupported proxy_localhost: %s", c.ProxyLocalhost))
+	}
+	if c.MaxConnections < 0 {
+		errs = append(errs, errors.New("max_connections: must be >= 0"))
 	}
 	if err := validateProxyURL(c.UpstreamProxy); err != nil {
-		return fmt.Errorf("upstream_proxy_uri: %w", err)
+		errs = append(errs, fmt.Errorf("upstream_proxy_uri: %w", err))
+	}
+	if err := validateNoProxy(c.NoProxy); err != nil {
+		errs = append(errs, fmt.Errorf("no_proxy: %w", err))
 	}
 
-	return nil
+	return errors.Join(errs...)
 }
 
 type HTTPProxy struct {
@@ -145,8 +160,15 @@ type HTTPProxy struct {
 	mitmCACert *x509.Certificate
 	proxyFunc  ProxyFunc
 
+	// direct matches host or host:port of requests that go direct, as specified by DirectDomains and NoProxy.
+	direct Matcher
+
 	tlsConfig *tls.Config
 	listener  net.Listener
+
+	// insecureCreds holds credentials that were warned about being sent over plain HTTP,
+	// the keys are *url.Userinfo returned by creds, so the size is bounded by the number of credentials.
+	insecureCreds sync.Map
 }
 
 // NewHTTPProxy creates a new HTTP proxy.
@@ -280,7 +302,10 @@ func (hp *HTTPProxy) configureProxy() error {
 		hp.log.Infof("no upstream proxy specified")
 	}
 
-	if hp.config.DirectDomains != nil {
+	if err := hp.configureDirect(); err != nil {
+		return err
+	}
+	if hp.direct != nil {
 		hp.proxyFunc = hp.directDomains(hp.proxyFunc)
 	}
 
@@ -420,19 +445,67 @@ func (hp *HTTPProxy) denyDomains(r Matcher) martian.RequestModifier {
 	})
 }
 
+// configureDirect sets up a single set of rules for direct connections from DirectDomains and NoProxy.
+// DirectDomains match the host name, NoProxy entries may also match the port.
+func (hp *HTTPProxy) configureDirect() error {
+	var ms []Matcher
+
+	if dd := hp.config.DirectDomains; dd != nil {
+		ms = append(ms, MatchFunc(func(hostport string) bool {
+			host, _, err := net.SplitHostPort(hostport)
+			if err != nil {
+				host = hostport
+			}
+			return dd.Match(host)
+		}))
+	}
+	if len(hp.config.NoProxy) > 0 {
+		m, err := NewNoProxyMatcher(hp.config.NoProxy)
+		if err != nil {
+			return fmt.Errorf("no_proxy: %w", err)
+		}
+		ms = append(ms, m)
+	}
+
+	if len(ms) > 0 {
+		hp.direct = anyMatcher(ms...)
+	}
+	return nil
+}
+
 func (hp *HTTPProxy) directDomains(fn ProxyFunc) ProxyFunc {
 	if fn == nil {
 		return nil
 	}
 
 	return func(req *http.Request) (*url.URL, error) {
-		if hp.config.DirectDomains.Match(req.URL.Hostname()) {
+		if hp.direct.Match(requestHostport(req)) {
 			return nil, nil
 		}
 		return fn(req)
 	}
 }
 
+// requestHostport returns host:port of the request URL, if the port is not set the scheme default is used.
+func requestHostport(req *http.Request) string {
+	if req.URL.Port() == "" {
+		switch req.URL.Scheme {
+		case "http":
+			return net.JoinHostPort(req.URL.Hostname(), "80")
+		case "https":
+			return net.JoinHostPort(req.URL.Hostname(), "443")
+		}
+	}
+	return req.URL.Host
+}
+
+// ShouldBypass returns true if host or host:port matches DirectDomains or NoProxy.
+// It does not account for localhost proxying mode or PAC results,
+// use ProxyFunc to get the upstream proxy for a request.
+func (hp *HTTPProxy) ShouldBypass(host string) bool {
+	return hp.direct != nil && hp.direct.Match(host)
+}
+
 func (hp *HTTPProxy) directLocalhost(fn ProxyFunc) ProxyFunc {
 	if fn == nil {
 		return nil
@@ -447,12 +520,15 @@ func (hp *HTTPProxy) directLocalhost(fn ProxyFunc) ProxyFunc {
 }
 
 func (hp *HTTPProxy) isLocalhost(req *http.Request) bool {
-	return isLocalhost(req.URL.Hostname())
+	return IsLocalhost(req.URL.Hostname())
 }
 
 func (hp *HTTPProxy) setBasicAuth(req *http.Request) error {
 	if req.Header.Get("Authorization") == "" {
 		if u := hp.creds.MatchURL(req.URL); u != nil {
+			if req.URL.Scheme == "http" && !hp.config.AllowInsecureCredentials {
+				hp.warnInsecureCredentials(u, req.URL.Host)
+			}
 			p, _ := u.Password()
 			req.SetBasicAuth(u.Username(), p)
 		}
@@ -461,6 +537,14 @@ func (hp *HTTPProxy) setBasicAuth(req *http.Request) error {
 	return nil
 }
 
+// warnInsecureCredentials logs a warning the first time the credentials are sent over plain HTTP.
+func (hp *HTTPProxy) warnInsecureCredentials(u *url.Userinfo, host string) {
+	if _, loaded := hp.insecureCreds.LoadOrStore(u, struct{}{}); loaded {
+		return
+	}
+	hp.log.Infof("sending unencrypted credentials over plain HTTP host=%s user=%s", host, u.Username())
+}
+
 func setEmptyUserAgent(req *http.Request) error {
 	if _, ok := req.Header["User-Agent"]; !ok {
 		// If the outbound request doesn't have a User-Agent header set,
@@ -540,6 +624,7 @@ func (hp *HTTPProxy) listen() (net.Listener, error) {
 		TLSHandshakeTimeout: hp.config.TLSServerConfig.HandshakeTimeout,
 		ReadLimit:           int64(hp.config.ReadLimit),
 		WriteLimit:          int64(hp.config.WriteLimit),
+		MaxConns:            hp.config.MaxConnections,
 		PromConfig: PromConfig{
 			PromNamespace: hp.config.PromNamespace,
 			PromRegistry:  hp.config.PromRegistry,
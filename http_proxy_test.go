@@ -9,10 +9,13 @@ package forwarder
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/saucelabs/forwarder/log/stdlog"
@@ -109,3 +112,166 @@ func TestNopDialer(t *testing.T) {
 		t.Fatalf("expected %v, got %v", nopDialerErr, err)
 	}
 }
+
+func TestHTTPProxyNoProxy(t *testing.T) {
+	cfg := DefaultHTTPProxyConfig()
+	cfg.UpstreamProxy = &url.URL{Scheme: "http", Host: "proxy:3128"}
+	cfg.NoProxy = []string{".internal", "10.0.0.0/8", "example.org:8080"}
+	cfg.DirectDomains = MatchFunc(func(host string) bool { return host == "direct.example.com" })
+
+	p, err := NewHTTPProxy(cfg, nil, nil, nil, stdlog.Default())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	if cfg.DirectDomains.Match("api.internal") {
+		t.Error("expected DirectDomains of the config not to be modified")
+	}
+
+	for _, h := range []string{"api.internal", "10.20.30.40", "direct.example.com", "direct.example.com:8080", "example.org:8080"} {
+		if !p.ShouldBypass(h) {
+			t.Errorf("expected %s to bypass proxy", h)
+		}
+		req := &http.Request{URL: &url.URL{Scheme: "http", Host: h}}
+		if u, err := p.ProxyFunc()(req); err != nil || u != nil {
+			t.Errorf("expected %s to go direct, got %v, %v", h, u, err)
+		}
+	}
+	for _, h := range []string{"saucelabs.com", "example.org", "example.org:443"} {
+		if p.ShouldBypass(h) {
+			t.Errorf("expected %s not to bypass proxy", h)
+		}
+		req := &http.Request{URL: &url.URL{Scheme: "https", Host: h}}
+		if u, err := p.ProxyFunc()(req); err != nil || u == nil {
+			t.Errorf("expected %s to use upstream proxy, got %v, %v", h, u, err)
+		}
+	}
+}
+
+func TestHTTPProxyConfigNoProxyValidate(t *testing.T) {
+	cfg := DefaultHTTPProxyConfig()
+	cfg.NoProxy = []string{"10.0.0.0/80"}
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("expected error for malformed CIDR")
+	}
+}
+
+func TestHTTPProxyConfigValidateMultipleErrors(t *testing.T) {
+	cfg := DefaultHTTPProxyConfig()
+	cfg.Protocol = "ftp"
+	cfg.UpstreamProxy = &url.URL{Scheme: "tcp", Host: "1.2.3.4:1080"}
+	cfg.NoProxy = []string{"10.0.0.0/80"}
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	for _, want := range []string{"unsupported protocol: ftp", "upstream_proxy_uri: unsupported scheme", "no_proxy: invalid CIDR"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error to contain %q, got %q", want, err)
+		}
+	}
+}
+
+type recordingLogger struct {
+	mu   sync.Mutex
+	logs []string
+}
+
+func (l *recordingLogger) Errorf(format string, args ...any) {
+	l.record(format, args...)
+}
+
+func (l *recordingLogger) Infof(format string, args ...any) {
+	l.record(format, args...)
+}
+
+func (l *recordingLogger) Debugf(format string, args ...any) {
+	l.record(format, args...)
+}
+
+func (l *recordingLogger) record(format string, args ...any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.logs = append(l.logs, fmt.Sprintf(format, args...))
+}
+
+func (l *recordingLogger) count(substr string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	n := 0
+	for _, s := range l.logs {
+		if strings.Contains(s, substr) {
+			n++
+		}
+	}
+	return n
+}
+
+func TestSetBasicAuthInsecureCredentialsWarning(t *testing.T) {
+	hpu, err := ParseHostPortUser("user:pass@foobar")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const warning = "sending unencrypted credentials over plain HTTP host=foobar user=user"
+
+	tests := []struct {
+		name  string
+		allow bool
+		urls  []string
+		warns int
+	}{
+		{
+			name:  "http",
+			urls:  []string{"http://foobar/a", "http://foobar/b"},
+			warns: 1,
+		},
+		{
+			name: "https",
+			urls: []string{"https://foobar/a"},
+		},
+		{
+			name:  "http allowed",
+			allow: true,
+			urls:  []string{"http://foobar/a"},
+		},
+	}
+
+	for i := range tests {
+		tc := &tests[i]
+		t.Run(tc.name, func(t *testing.T) {
+			l := new(recordingLogger)
+			cm, err := NewCredentialsMatcher([]*HostPortUser{hpu}, l)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			cfg := DefaultHTTPProxyConfig()
+			cfg.AllowInsecureCredentials = tc.allow
+			p, err := NewHTTPProxy(cfg, nil, cm, nil, l)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer p.Close()
+
+			for _, u := range tc.urls {
+				req, err := http.NewRequest(http.MethodGet, u, http.NoBody)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := p.setBasicAuth(req); err != nil {
+					t.Fatal(err)
+				}
+				if _, _, ok := req.BasicAuth(); !ok {
+					t.Fatal("expected credentials to be set")
+				}
+			}
+
+			if n := l.count(warning); n != tc.warns {
+				t.Fatalf("expected %d warnings, got %d: %v", tc.warns, n, l.logs)
+			}
+		})
+	}
+}
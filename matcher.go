@@ -6,6 +6,14 @@
 
 package forwarder
 
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
 type Matcher interface {
 	Match(string) bool
 }
@@ -15,3 +23,158 @@ type MatchFunc func(string) bool
 func (m MatchFunc) Match(s string) bool {
 	return m(s)
 }
+
+// anyMatcher returns a Matcher that matches if any of the non-nil matchers matches.
+func anyMatcher(ms ...Matcher) Matcher {
+	var nn []Matcher
+	for _, m := range ms {
+		if m != nil {
+			nn = append(nn, m)
+		}
+	}
+	if len(nn) == 1 {
+		return nn[0]
+	}
+
+	return MatchFunc(func(s string) bool {
+		for _, m := range nn {
+			if m.Match(s) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// NewNoProxyMatcher returns a Matcher that implements the well-known NO_PROXY semantics.
+// The following entries are supported:
+//   - "*" matches all hosts,
+//   - IP address matches the exact IP,
+//   - CIDR range (e.g. 10.0.0.0/8) matches all IPs in the range,
+//   - domain name (e.g. example.com) matches the domain and all its subdomains,
+//   - domain suffix (e.g. .example.com or *.example.com) matches subdomains only.
+//
+// Except for "*" and CIDR ranges, an entry may have a port (e.g. example.com:8080 or [::1]:8080),
+// such entry matches only the given port.
+// The matcher accepts a host or host:port, a host without port matches only entries without port.
+func NewNoProxyMatcher(entries []string) (Matcher, error) {
+	rules := make([]noProxyRule, len(entries))
+	for i, e := range entries {
+		r, err := parseNoProxyRule(e, i)
+		if err != nil {
+			return nil, err
+		}
+		rules[i] = r
+	}
+
+	return MatchFunc(func(s string) bool {
+		host, port := s, ""
+		if h, p, err := net.SplitHostPort(s); err == nil {
+			host, port = h, p
+		}
+		host = strings.TrimSuffix(strings.ToLower(strings.Trim(host, "[]")), ".")
+
+		var ip netip.Addr
+		if v, err := netip.ParseAddr(host); err == nil {
+			ip = v.Unmap()
+		}
+
+		for i := range rules {
+			if rules[i].match(host, ip, port) {
+				return true
+			}
+		}
+		return false
+	}), nil
+}
+
+// validateNoProxy checks the NO_PROXY entries, see NewNoProxyMatcher for the supported syntax.
+func validateNoProxy(entries []string) error {
+	for i, e := range entries {
+		if _, err := parseNoProxyRule(e, i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type noProxyRule struct {
+	all    bool
+	prefix netip.Prefix // IP addresses are stored as single address prefixes.
+	domain string       // Matches the domain and its subdomains.
+	suffix string       // Matches subdomains only, it starts with a dot.
+	port   string       // If set, only the given port matches.
+}
+
+func parseNoProxyRule(e string, i int) (noProxyRule, error) {
+	var r noProxyRule
+
+	e = strings.ToLower(strings.TrimSpace(e))
+
+	switch {
+	case e == "":
+		return r, fmt.Errorf("empty entry at pos %d", i)
+	case e == "*":
+		r.all = true
+		return r, nil
+	case strings.Contains(e, "/"):
+		p, err := netip.ParsePrefix(e)
+		if err != nil {
+			return r, fmt.Errorf("invalid CIDR %q at pos %d: %w", e, i, err)
+		}
+		r.prefix = p.Masked()
+		return r, nil
+	}
+
+	host := e
+	if h, p, err := net.SplitHostPort(e); err == nil {
+		if n, err := strconv.ParseUint(p, 10, 16); err != nil || n == 0 {
+			return r, fmt.Errorf("invalid port %q at pos %d", e, i)
+		}
+		host, r.port = h, p
+	}
+
+	if strings.HasPrefix(host, "*.") || strings.HasPrefix(host, ".") {
+		d, ok := strings.CutPrefix(host, "*.")
+		if !ok {
+			d = strings.TrimPrefix(host, ".")
+		}
+		if !isDomainName(d) {
+			return r, fmt.Errorf("invalid domain suffix %q at pos %d", e, i)
+		}
+		r.suffix = "." + d
+		return r, nil
+	}
+
+	if ip, err := netip.ParseAddr(strings.Trim(host, "[]")); err == nil {
+		ip = ip.Unmap()
+		r.prefix = netip.PrefixFrom(ip, ip.BitLen())
+		return r, nil
+	}
+	if !isDomainName(host) {
+		return r, fmt.Errorf("invalid host %q at pos %d", e, i)
+	}
+	r.domain = host
+
+	return r, nil
+}
+
+// match reports whether the rule matches the normalized host, ip is the host IP or zero value if host is not an IP.
+func (r *noProxyRule) match(host string, ip netip.Addr, port string) bool {
+	if r.port != "" && r.port != port {
+		return false
+	}
+
+	switch {
+	case r.all:
+		return true
+	case r.prefix.IsValid():
+		return ip.IsValid() && r.prefix.Contains(ip)
+	case ip.IsValid():
+		return false
+	case r.domain != "":
+		return host == r.domain || strings.HasSuffix(host, "."+r.domain)
+	default:
+		return strings.HasSuffix(host, r.suffix)
+	}
+}
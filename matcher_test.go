@@ -0,0 +1,110 @@
+// Copyright 2022-2024 Sauce Labs Inc., all rights reserved.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package forwarder
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNoProxyMatcher(t *testing.T) {
+	entries := []string{
+		"example.com",
+		".internal",
+		"*.corp.local",
+		"10.0.0.0/8",
+		"192.168.1.1",
+		"fd00::/8",
+		"api.example.org:8443",
+		"[2001:db8::2]:443",
+	}
+	m, err := NewNoProxyMatcher(entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		host  string
+		match bool
+	}{
+		{"example.com", true},
+		{"EXAMPLE.com.", true},
+		{"www.example.com", true},
+		{"notexample.com", false},
+		{"foo.internal", true},
+		{"internal", false},
+		{"a.b.corp.local", true},
+		{"corp.local", false},
+		{"10.1.2.3", true},
+		{"11.1.2.3", false},
+		{"192.168.1.1", true},
+		{"192.168.1.2", false},
+		{"fd12::1", true},
+		{"[fd12::1]", true},
+		{"2001:db8::1", false},
+		{"saucelabs.com", false},
+		{"example.com:443", true},
+		{"10.1.2.3:80", true},
+		{"[fd12::1]:443", true},
+		{"api.example.org:8443", true},
+		{"api.example.org:443", false},
+		{"api.example.org", false},
+		{"[2001:db8::2]:443", true},
+		{"[2001:db8::2]:80", false},
+		{"2001:db8::2", false},
+	}
+
+	for i := range tests {
+		tc := tests[i]
+		if got := m.Match(tc.host); got != tc.match {
+			t.Errorf("Match(%q) = %v; want %v", tc.host, got, tc.match)
+		}
+	}
+}
+
+func TestNoProxyMatcherWildcard(t *testing.T) {
+	m, err := NewNoProxyMatcher([]string{"*"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, h := range []string{"example.com", "127.0.0.1", "::1"} {
+		if !m.Match(h) {
+			t.Errorf("Match(%q) = false; want true", h)
+		}
+	}
+}
+
+func TestNoProxyMatcherErrors(t *testing.T) {
+	tests := []struct {
+		entry string
+		err   string
+	}{
+		{"10.0.0.0/33", "invalid CIDR"},
+		{"10.0.0.300/8", "invalid CIDR"},
+		{"foo_bar-", "invalid host"},
+		{".", "invalid domain suffix"},
+		{"..foo", "invalid domain suffix"},
+		{"*.*.foo", "invalid domain suffix"},
+		{"*..foo", "invalid domain suffix"},
+		{"", "empty entry"},
+		{"example.com:0", "invalid port"},
+		{"example.com:http", "invalid port"},
+		{"example.com:", "invalid port"},
+		{"foo_bar-:80", "invalid host"},
+	}
+
+	for i := range tests {
+		tc := tests[i]
+		_, err := NewNoProxyMatcher([]string{tc.entry})
+		if err == nil {
+			t.Fatalf("NewNoProxyMatcher(%q): expected error", tc.entry)
+		}
+		if !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("NewNoProxyMatcher(%q): expected error to contain %q, got %q", tc.entry, tc.err, err)
+		}
+	}
+}
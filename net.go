@@ -18,6 +18,7 @@ import (
 
 	"github.com/saucelabs/forwarder/log"
 	"github.com/saucelabs/forwarder/ratelimit"
+	"golang.org/x/net/netutil"
 )
 
 type DialConfig struct {
@@ -110,6 +111,10 @@ type Listener struct {
 	TLSHandshakeTimeout time.Duration
 	ReadLimit           int64
 	WriteLimit          int64
+	// MaxConns limits the number of simultaneously open connections.
+	// When the limit is reached, new connections wait in the accept queue until a connection is closed.
+	// Zero means no limit.
+	MaxConns int
 	PromConfig
 
 	listener net.Listener
@@ -126,6 +131,10 @@ func (l *Listener) Listen() error {
 		return err
 	}
 
+	if l.MaxConns > 0 {
+		ll = netutil.LimitListener(ll, l.MaxConns)
+	}
+
 	if rl, wl := l.ReadLimit, l.WriteLimit; rl > 0 || wl > 0 {
 		ll = ratelimit.NewListener(ll, rl, wl)
 	}
@@ -13,6 +13,7 @@ import (
 	"fmt"
 	"io"
 	"net"
+	"os"
 	"testing"
 	"time"
 
@@ -282,3 +283,46 @@ func selfSingedCert() *tls.Config {
 		Certificates: []tls.Certificate{cert},
 	}
 }
+
+func TestListenerMaxConns(t *testing.T) {
+	l := Listener{
+		Address:  "localhost:0",
+		Log:      log.NopLogger,
+		MaxConns: 2,
+	}
+	defer l.Close()
+
+	l.listenAndWait(t)
+	go l.acceptAndCopy()
+
+	echo := func(conn net.Conn, timeout time.Duration) error {
+		fmt.Fprintf(conn, "Hello, World!\n")
+		conn.SetReadDeadline(time.Now().Add(timeout))
+		_, err := conn.Read(make([]byte, 1))
+		return err
+	}
+
+	conns := make([]net.Conn, 3)
+	for i := range conns {
+		conn, err := net.Dial("tcp", l.Addr().String())
+		if err != nil {
+			t.Fatalf("net.Dial(): got %v, want no error", err)
+		}
+		defer conn.Close()
+		conns[i] = conn
+	}
+
+	for _, conn := range conns[:2] {
+		if err := echo(conn, time.Second); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := echo(conns[2], 100*time.Millisecond); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatalf("expected connection over the limit to wait, got %v", err)
+	}
+
+	conns[0].Close()
+	if err := echo(conns[2], time.Second); err != nil {
+		t.Fatalf("expected connection to be accepted after another one was closed, got %v", err)
+	}
+}
@@ -15,8 +15,15 @@ import (
 	"net/url"
 	"os"
 	"strings"
+
+	"github.com/saucelabs/forwarder/fileurl"
 )
 
+// ParseReadURL parses a local file path or URL and checks that it can be read with ReadURL.
+func ParseReadURL(val string) (*url.URL, error) {
+	return fileurl.ParseFilePathOrURLAllowed(val, "data", "http", "https")
+}
+
 // ReadURLString can read base64 encoded data, local file, http or https URL or stdin and return it as a string.
 func ReadURLString(u *url.URL, rt http.RoundTripper) (string, error) {
 	b, err := ReadURL(u, rt)
@@ -36,7 +43,7 @@ func ReadURL(u *url.URL, rt http.RoundTripper) ([]byte, error) {
 	case "http", "https":
 		return readHTTP(u, rt)
 	default:
-		return nil, fmt.Errorf("unsupported scheme %q, supported schemes are: file, http and https", u.Scheme)
+		return nil, fmt.Errorf("unsupported scheme %q, supported schemes are: file, data, http and https", u.Scheme)
 	}
 }
 
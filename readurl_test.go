@@ -8,6 +8,7 @@ package forwarder
 
 import (
 	"net/url"
+	"strings"
 	"testing"
 )
 
@@ -72,3 +73,34 @@ func TestReadFileOrBase64(t *testing.T) {
 		})
 	}
 }
+
+func TestParseReadURL(t *testing.T) {
+	tests := []struct {
+		input string
+		err   string
+	}{
+		{input: "http://example.com/proxy.pac"},
+		{input: "https://example.com/proxy.pac"},
+		{input: "file:///tmp/wpad.dat"},
+		{input: "/tmp/wpad.dat"},
+		{input: "-"},
+		{input: "data:base64,Zm9v"},
+		{input: "ftp://example.com/proxy.pac", err: "unsupported scheme \"ftp\""},
+	}
+
+	for i := range tests {
+		tc := &tests[i]
+		t.Run(tc.input, func(t *testing.T) {
+			_, err := ParseReadURL(tc.input)
+			if tc.err == "" {
+				if err != nil {
+					t.Fatalf("expected success, got %q", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Fatalf("expected error to contain %q, got %v", tc.err, err)
+			}
+		})
+	}
+}
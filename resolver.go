@@ -8,6 +8,7 @@ package forwarder
 
 import (
 	"net"
+	"strings"
 	_ "unsafe" // for go:linkname
 )
 
@@ -17,8 +18,11 @@ import (
 //go:linkname lookupStaticHost net.lookupStaticHost
 func lookupStaticHost(string) ([]string, string)
 
-func isLocalhost(host string) bool {
-	if host == "localhost" || host == "0.0.0.0" || host == "::" {
+// IsLocalhost returns true if host is localhost, a name under the .localhost TLD (RFC 6761),
+// a loopback or unspecified IP address, or a name that resolves to a loopback address in /etc/hosts.
+func IsLocalhost(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") || host == "0.0.0.0" || host == "::" {
 		return true
 	}
 
@@ -17,11 +17,18 @@ func TestIsLocalhost(t *testing.T) {
 	}{
 		{"127.0.0.1", true},
 		{"127.10.20.30", true},
+		{"127.0.0.5", true},
 		{"localhost", true},
+		{"localhost.", true},
+		{"LocalHost", true},
+		{"foo.localhost", true},
 		{"0.0.0.0", true},
 
 		{"notlocalhost", false},
 		{"broadcasthost", false},
+		{"localhost.example.com", false},
+		{"foolocalhost", false},
+		{"saucelabs.com", false},
 
 		{"::1", true},
 		{"::", true},
@@ -32,8 +39,8 @@ func TestIsLocalhost(t *testing.T) {
 
 	for i := range tests {
 		tc := tests[i]
-		if lh := isLocalhost(tc.host); lh != tc.localhost {
-			t.Errorf("isLocalhost(%q) = %v; want %v", tc.host, lh, tc.localhost)
+		if lh := IsLocalhost(tc.host); lh != tc.localhost {
+			t.Errorf("IsLocalhost(%q) = %v; want %v", tc.host, lh, tc.localhost)
 		}
 	}
 }
@@ -9,6 +9,7 @@ package forwarder
 import (
 	"crypto/tls"
 	"crypto/x509"
+	"errors"
 	"fmt"
 	"os"
 	"time"
@@ -32,6 +33,12 @@ type TLSClientConfig struct {
 	// CACertFiles is a list of paths to CA certificate files.
 	// If this is set, the system root CA pool will be supplemented with certificates from these files.
 	CACertFiles []string
+
+	// CertFile is the path to the client certificate used for mutual TLS.
+	CertFile string
+
+	// KeyFile is the path to the private key of the client certificate.
+	KeyFile string
 }
 
 func DefaultTLSClientConfig() *TLSClientConfig {
@@ -47,6 +54,27 @@ func (c *TLSClientConfig) ConfigureTLSConfig(tlsCfg *tls.Config) error {
 		return fmt.Errorf("load CAs: %w", err)
 	}
 
+	if err := c.loadCertificate(tlsCfg); err != nil {
+		return fmt.Errorf("load client certificate: %w", err)
+	}
+
+	return nil
+}
+
+func (c *TLSClientConfig) loadCertificate(tlsCfg *tls.Config) error {
+	if c.CertFile == "" && c.KeyFile == "" {
+		return nil
+	}
+	if c.CertFile == "" || c.KeyFile == "" {
+		return errors.New("both certificate and key files must be specified")
+	}
+
+	cert, err := loadX509KeyPair(c.CertFile, c.KeyFile)
+	if err != nil {
+		return err
+	}
+	tlsCfg.Certificates = append(tlsCfg.Certificates, cert)
+
 	return nil
 }
 
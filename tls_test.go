@@ -0,0 +1,104 @@
+// Copyright 2022-2024 Sauce Labs Inc., all rights reserved.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package forwarder
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/saucelabs/forwarder/utils/certutil"
+)
+
+func TestTLSClientConfigClientCert(t *testing.T) {
+	ca, err := certutil.NewCA(certutil.ECDSASelfSignedCert())
+	if err != nil {
+		t.Fatal(err)
+	}
+	serverCert, err := ca.IssueCert("127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientCert, err := ca.IssueCert("client")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.crt")
+	if err := certutil.SaveCertPEM(ca.Cert, caFile, filepath.Join(dir, "ca.key")); err != nil {
+		t.Fatal(err)
+	}
+	certFile, keyFile := filepath.Join(dir, "client.crt"), filepath.Join(dir, "client.key")
+	if err := certutil.SaveCertPEM(clientCert, certFile, keyFile); err != nil {
+		t.Fatal(err)
+	}
+
+	s := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	s.TLS = &tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		ClientAuth:   tls.RequireAnyClientCert,
+	}
+	s.StartTLS()
+	defer s.Close()
+
+	tests := []struct {
+		name     string
+		certFile string
+		keyFile  string
+		err      string
+	}{
+		{
+			name:     "client cert",
+			certFile: certFile,
+			keyFile:  keyFile,
+		},
+		{
+			name: "no client cert",
+			err:  "certificate required",
+		},
+		{
+			name:     "missing key",
+			certFile: certFile,
+			err:      "both certificate and key files must be specified",
+		},
+	}
+
+	for i := range tests {
+		tc := &tests[i]
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := DefaultHTTPTransportConfig()
+			cfg.CACertFiles = []string{caFile}
+			cfg.CertFile = tc.certFile
+			cfg.KeyFile = tc.keyFile
+
+			tr, err := NewHTTPTransport(cfg)
+			if err == nil {
+				var resp *http.Response
+				resp, err = (&http.Client{Transport: tr}).Get(s.URL)
+				if err == nil {
+					resp.Body.Close()
+				}
+			}
+
+			if tc.err == "" {
+				if err != nil {
+					t.Fatalf("expected success, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Fatalf("expected error to contain %q, got %v", tc.err, err)
+			}
+		})
+	}
+}
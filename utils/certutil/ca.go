@@ -0,0 +1,137 @@
+// Copyright 2022-2024 Sauce Labs Inc., all rights reserved.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package certutil
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// CA is a certificate authority that issues leaf certificates.
+type CA struct {
+	Cert     tls.Certificate
+	ValidFor time.Duration
+	// Rand is the source of randomness for issued certificates, see SelfSignedCert.Rand.
+	// If nil, crypto/rand.Reader is used.
+	Rand io.Reader
+
+	x509 *x509.Certificate
+}
+
+// NewCA generates a self-signed CA certificate specified by c.
+// The Hosts field is ignored and IsCA is set to true.
+// Leaf certificates issued by the CA are valid for c.ValidFor but not longer than the CA,
+// they use c.Rand as the source of randomness.
+func NewCA(c *SelfSignedCert) (*CA, error) {
+	tmpl := *c
+	tmpl.Hosts = nil
+	tmpl.IsCA = true
+
+	cert, err := tmpl.Gen()
+	if err != nil {
+		return nil, err
+	}
+
+	ca, err := NewCAFromCert(cert, c.ValidFor)
+	if err != nil {
+		return nil, err
+	}
+	ca.Rand = c.Rand
+
+	return ca, nil
+}
+
+// NewCAFromCert returns a CA for an existing CA certificate.
+func NewCAFromCert(cert tls.Certificate, validFor time.Duration) (*CA, error) {
+	if len(cert.Certificate) == 0 {
+		return nil, errors.New("certificate is empty")
+	}
+	x, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return nil, fmt.Errorf("parse certificate %w", err)
+	}
+	if !x.IsCA {
+		return nil, errors.New("certificate is not a CA")
+	}
+	if validFor <= 0 {
+		return nil, fmt.Errorf("invalid validity period %s, must be positive", validFor)
+	}
+
+	return &CA{
+		Cert:     cert,
+		ValidFor: validFor,
+		x509:     x,
+	}, nil
+}
+
+// IssueCert generates an ECDSA P256 leaf certificate for the hosts signed by the CA.
+// The returned certificate chain includes the CA certificate.
+func (ca *CA) IssueCert(hosts ...string) (tls.Certificate, error) {
+	var cert tls.Certificate
+
+	if len(hosts) == 0 {
+		return cert, errors.New("no hosts specified")
+	}
+
+	r := ca.rand()
+
+	// Serial number is generated first as key generation may read a random number of bytes.
+	serialNumber, err := newSerialNumber(r)
+	if err != nil {
+		return cert, err
+	}
+
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), r)
+	if err != nil {
+		return cert, fmt.Errorf("generate private key %w", err)
+	}
+
+	now := time.Now()
+	notAfter := now.Add(ca.ValidFor)
+	if notAfter.After(ca.x509.NotAfter) {
+		notAfter = ca.x509.NotAfter
+	}
+
+	template := x509.Certificate{
+		SerialNumber: serialNumber,
+		Subject: pkix.Name{
+			CommonName:   hosts[0],
+			Organization: ca.x509.Subject.Organization,
+		},
+		NotBefore: now,
+		NotAfter:  notAfter,
+
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
+	addHosts(&template, hosts)
+
+	derBytes, err := x509.CreateCertificate(r, &template, ca.x509, &priv.PublicKey, ca.Cert.PrivateKey)
+	if err != nil {
+		return cert, fmt.Errorf("create certificate %w", err)
+	}
+	cert.Certificate = [][]byte{derBytes, ca.Cert.Certificate[0]}
+	cert.PrivateKey = priv
+
+	return cert, nil
+}
+
+func (ca *CA) rand() io.Reader {
+	if ca.Rand != nil {
+		return ca.Rand
+	}
+	return rand.Reader
+}
@@ -0,0 +1,84 @@
+// Copyright 2022-2024 Sauce Labs Inc., all rights reserved.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+//go:build !windows
+
+package certutil
+
+import (
+	"crypto/x509"
+	"math/big"
+	mathrand "math/rand"
+	"testing"
+	"time"
+)
+
+func TestCAIssueCert(t *testing.T) {
+	ca, err := NewCA(ECDSASelfSignedCert())
+	if err != nil {
+		t.Fatalf("NewCA() error %s", err)
+	}
+	root, err := x509.ParseCertificate(ca.Cert.Certificate[0])
+	if err != nil {
+		t.Fatalf("x509.ParseCertificate() error %s", err)
+	}
+	if !root.IsCA || root.KeyUsage&x509.KeyUsageCertSign == 0 {
+		t.Fatalf("expected CA certificate with cert sign key usage")
+	}
+
+	cert, err := ca.IssueCert("foo.test", "127.0.0.1")
+	if err != nil {
+		t.Fatalf("CA.IssueCert() error %s", err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatalf("x509.ParseCertificate() error %s", err)
+	}
+	if leaf.IsCA {
+		t.Fatal("expected leaf certificate")
+	}
+
+	if err := getTLS(&cert, root, "foo.test"); err != nil {
+		t.Fatalf("http.Get() error %s", err)
+	}
+	if err := getTLS(&cert, root, "bar.test"); err == nil {
+		t.Fatal("http.Get() expected error for bar.test")
+	}
+}
+
+func TestCAIssueCertRand(t *testing.T) {
+	ca, err := NewCA(ECDSASelfSignedCert())
+	if err != nil {
+		t.Fatalf("NewCA() error %s", err)
+	}
+
+	serial := func() *big.Int {
+		ca.Rand = mathrand.New(mathrand.NewSource(1)) //nolint:gosec // deterministic test
+		cert, err := ca.IssueCert("foo.test")
+		if err != nil {
+			t.Fatalf("CA.IssueCert() error %s", err)
+		}
+		x, err := x509.ParseCertificate(cert.Certificate[0])
+		if err != nil {
+			t.Fatalf("x509.ParseCertificate() error %s", err)
+		}
+		return x.SerialNumber
+	}
+
+	if a, b := serial(), serial(); a.Cmp(b) != 0 {
+		t.Fatalf("expected equal serial numbers, got %s and %s", a, b)
+	}
+}
+
+func TestNewCAFromCertNotCA(t *testing.T) {
+	cert, err := ECDSASelfSignedCert().Gen()
+	if err != nil {
+		t.Fatalf("ECDSASelfSignedCert.Gen() error %s", err)
+	}
+	if _, err := NewCAFromCert(cert, time.Hour); err == nil {
+		t.Fatal("NewCAFromCert() expected error for non CA certificate")
+	}
+}
@@ -16,25 +16,40 @@ import (
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"fmt"
+	"io"
 	"math/big"
 	"net"
+	"slices"
 	"time"
 )
 
 // SelfSignedCert specifies a self-signed certificate to be generated.
 type SelfSignedCert struct {
 	Hosts        []string
+	CommonName   string
 	Organization []string
 	ValidFrom    time.Time
 	ValidFor     time.Duration
 	IsCA         bool
-	RsaBits      int
-	EcdsaCurve   string
-	Ed25519Key   bool
+	// RsaBits is the RSA key size, one of 1024, 2048, 3072 or 4096.
+	// 1024 bit keys are insecure and should be used for testing only.
+	RsaBits    int
+	EcdsaCurve string
+	Ed25519Key bool
+	// Rand is the source of randomness for the serial number, key generation and signing.
+	// If nil, crypto/rand.Reader is used.
+	// With a seeded reader only the serial number is deterministic,
+	// key generation and signing may ignore it or mix in other randomness, see crypto/ecdsa.GenerateKey.
+	Rand io.Reader
 }
 
+const defaultCommonName = "Forwarder Self-Signed"
+
+var rsaKeySizes = []int{1024, 2048, 3072, 4096}
+
 func RSASelfSignedCert() *SelfSignedCert {
 	return &SelfSignedCert{
+		CommonName:   defaultCommonName,
 		Organization: []string{"Sauce Labs Inc."},
 		ValidFrom:    time.Now(),
 		ValidFor:     365 * 24 * time.Hour,
@@ -44,6 +59,7 @@ func RSASelfSignedCert() *SelfSignedCert {
 
 func ECDSASelfSignedCert() *SelfSignedCert {
 	return &SelfSignedCert{
+		CommonName:   defaultCommonName,
 		Organization: []string{"Sauce Labs Inc."},
 		ValidFrom:    time.Now(),
 		ValidFor:     365 * 24 * time.Hour,
@@ -52,10 +68,24 @@ func ECDSASelfSignedCert() *SelfSignedCert {
 	}
 }
 
+func Ed25519SelfSignedCert() *SelfSignedCert {
+	return &SelfSignedCert{
+		CommonName:   defaultCommonName,
+		Organization: []string{"Sauce Labs Inc."},
+		ValidFrom:    time.Now(),
+		ValidFor:     365 * 24 * time.Hour,
+		Ed25519Key:   true,
+	}
+}
+
 // Gen generates a self-signed certificate, the implementation is based on https://golang.org/src/crypto/tls/generate_cert.go.
 func (c *SelfSignedCert) Gen() (tls.Certificate, error) {
 	var cert tls.Certificate
 
+	if c.ValidFor <= 0 {
+		return cert, fmt.Errorf("invalid validity period %s, must be positive", c.ValidFor)
+	}
+
 	priv, err := c.generateKey()
 	if err != nil {
 		return cert, fmt.Errorf("generate private key %w", err)
@@ -71,15 +101,15 @@ func (c *SelfSignedCert) Gen() (tls.Certificate, error) {
 		keyUsage |= x509.KeyUsageKeyEncipherment
 	}
 
-	serialNumberLimit := new(big.Int).Lsh(big.NewInt(1), 128)
-	serialNumber, err := rand.Int(rand.Reader, serialNumberLimit)
+	serialNumber, err := newSerialNumber(c.rand())
 	if err != nil {
-		return cert, fmt.Errorf("generate serial number %w", err)
+		return cert, err
 	}
 
 	template := x509.Certificate{
 		SerialNumber: serialNumber,
 		Subject: pkix.Name{
+			CommonName:   c.CommonName,
 			Organization: c.Organization,
 		},
 		NotBefore: c.ValidFrom,
@@ -90,20 +120,14 @@ func (c *SelfSignedCert) Gen() (tls.Certificate, error) {
 		BasicConstraintsValid: true,
 	}
 
-	for _, h := range c.Hosts {
-		if ip := net.ParseIP(h); ip != nil {
-			template.IPAddresses = append(template.IPAddresses, ip)
-		} else {
-			template.DNSNames = append(template.DNSNames, h)
-		}
-	}
+	addHosts(&template, c.Hosts)
 
 	if c.IsCA {
 		template.IsCA = true
 		template.KeyUsage |= x509.KeyUsageCertSign
 	}
 
-	derBytes, err := x509.CreateCertificate(rand.Reader, &template, &template, publicKey(priv), priv)
+	derBytes, err := x509.CreateCertificate(c.rand(), &template, &template, publicKey(priv), priv)
 	if err != nil {
 		return cert, fmt.Errorf("create certificate %w", err)
 	}
@@ -113,22 +137,52 @@ func (c *SelfSignedCert) Gen() (tls.Certificate, error) {
 	return cert, nil
 }
 
+func (c *SelfSignedCert) rand() io.Reader {
+	if c.Rand != nil {
+		return c.Rand
+	}
+	return rand.Reader
+}
+
+func newSerialNumber(r io.Reader) (*big.Int, error) {
+	serialNumberLimit := new(big.Int).Lsh(big.NewInt(1), 128)
+	serialNumber, err := rand.Int(r, serialNumberLimit)
+	if err != nil {
+		return nil, fmt.Errorf("generate serial number %w", err)
+	}
+	return serialNumber, nil
+}
+
+func addHosts(template *x509.Certificate, hosts []string) {
+	for _, h := range hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, h)
+		}
+	}
+}
+
 func (c *SelfSignedCert) generateKey() (priv any, err error) {
+	r := c.rand()
 	switch c.EcdsaCurve {
 	case "":
 		if c.Ed25519Key {
-			_, priv, err = ed25519.GenerateKey(rand.Reader)
+			_, priv, err = ed25519.GenerateKey(r)
 		} else {
-			priv, err = rsa.GenerateKey(rand.Reader, c.RsaBits)
+			if !slices.Contains(rsaKeySizes, c.RsaBits) {
+				return nil, fmt.Errorf("unsupported RSA key size: %d", c.RsaBits)
+			}
+			priv, err = rsa.GenerateKey(r, c.RsaBits)
 		}
 	case "P224":
-		priv, err = ecdsa.GenerateKey(elliptic.P224(), rand.Reader)
+		priv, err = ecdsa.GenerateKey(elliptic.P224(), r)
 	case "P256":
-		priv, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		priv, err = ecdsa.GenerateKey(elliptic.P256(), r)
 	case "P384":
-		priv, err = ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+		priv, err = ecdsa.GenerateKey(elliptic.P384(), r)
 	case "P521":
-		priv, err = ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+		priv, err = ecdsa.GenerateKey(elliptic.P521(), r)
 	default:
 		err = fmt.Errorf("unrecognized elliptic curve: %q", c.EcdsaCurve)
 	}
@@ -9,11 +9,18 @@
 package certutil
 
 import (
+	"crypto/ed25519"
 	"crypto/tls"
 	"crypto/x509"
+	"fmt"
+	"math/big"
+	mathrand "math/rand"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestRSASelfSignedCertGen(t *testing.T) {
@@ -27,6 +34,33 @@ func TestRSASelfSignedCertGen(t *testing.T) {
 	testCert(t, &cert)
 }
 
+func TestRSASelfSignedCertKeySize(t *testing.T) {
+	c := RSASelfSignedCert()
+	c.RsaBits = 1024
+	if _, err := c.Gen(); err != nil {
+		t.Fatalf("RSASelfSignedCert.Gen() error %s", err)
+	}
+
+	c.RsaBits = 1000
+	if _, err := c.Gen(); err == nil || !strings.Contains(err.Error(), "unsupported RSA key size") {
+		t.Fatalf("RSASelfSignedCert.Gen() expected unsupported RSA key size error, got %v", err)
+	}
+}
+
+func BenchmarkRSASelfSignedCertGen(b *testing.B) {
+	for _, bits := range []int{1024, 2048, 3072, 4096} {
+		b.Run(fmt.Sprint(bits), func(b *testing.B) {
+			c := RSASelfSignedCert()
+			c.RsaBits = bits
+			for i := 0; i < b.N; i++ {
+				if _, err := c.Gen(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
 func TestECDSASelfSignedCertGen(t *testing.T) {
 	c := ECDSASelfSignedCert()
 	c.Hosts = []string{"127.0.0.1"}
@@ -38,7 +72,133 @@ func TestECDSASelfSignedCertGen(t *testing.T) {
 	testCert(t, &cert)
 }
 
+func TestEd25519SelfSignedCertGen(t *testing.T) {
+	c := Ed25519SelfSignedCert()
+	c.Hosts = []string{"127.0.0.1"}
+
+	cert, err := c.Gen()
+	if err != nil {
+		t.Fatalf("Ed25519SelfSignedCert.Gen() error %s", err)
+	}
+	if _, ok := cert.PrivateKey.(ed25519.PrivateKey); !ok {
+		t.Fatalf("expected ed25519 private key, got %T", cert.PrivateKey)
+	}
+	x, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatalf("x509.ParseCertificate() error %s", err)
+	}
+	if x.SignatureAlgorithm != x509.PureEd25519 {
+		t.Fatalf("expected signature algorithm %s, got %s", x509.PureEd25519, x.SignatureAlgorithm)
+	}
+	testCert(t, &cert)
+}
+
+func TestSelfSignedCertHosts(t *testing.T) {
+	c := RSASelfSignedCert()
+	c.Hosts = []string{"example.test", "127.0.0.1"}
+
+	cert, err := c.Gen()
+	if err != nil {
+		t.Fatalf("RSASelfSignedCert.Gen() error %s", err)
+	}
+	x, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatalf("x509.ParseCertificate() error %s", err)
+	}
+	if len(x.DNSNames) != 1 || x.DNSNames[0] != "example.test" {
+		t.Fatalf("expected DNS names [example.test], got %v", x.DNSNames)
+	}
+	if len(x.IPAddresses) != 1 || !x.IPAddresses[0].Equal(net.ParseIP("127.0.0.1")) {
+		t.Fatalf("expected IP addresses [127.0.0.1], got %v", x.IPAddresses)
+	}
+
+	if err := getTLS(&cert, x, "example.test"); err != nil {
+		t.Fatalf("http.Get() error %s", err)
+	}
+	if err := getTLS(&cert, x, "other.test"); err == nil {
+		t.Fatal("http.Get() expected error for other.test")
+	}
+}
+
+func TestSelfSignedCertSubject(t *testing.T) {
+	c := ECDSASelfSignedCert()
+
+	cert, err := c.Gen()
+	if err != nil {
+		t.Fatalf("ECDSASelfSignedCert.Gen() error %s", err)
+	}
+	x, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatalf("x509.ParseCertificate() error %s", err)
+	}
+	if x.Subject.CommonName != "Forwarder Self-Signed" {
+		t.Fatalf("expected default common name, got %q", x.Subject.CommonName)
+	}
+
+	c.CommonName = "dev.example.test"
+	c.Organization = []string{"Example Dev"}
+	cert, err = c.Gen()
+	if err != nil {
+		t.Fatalf("ECDSASelfSignedCert.Gen() error %s", err)
+	}
+	x, err = x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatalf("x509.ParseCertificate() error %s", err)
+	}
+	if x.Subject.CommonName != "dev.example.test" || len(x.Subject.Organization) != 1 || x.Subject.Organization[0] != "Example Dev" {
+		t.Fatalf("unexpected subject %s", x.Subject)
+	}
+}
+
+func TestSelfSignedCertRand(t *testing.T) {
+	serial := func() *big.Int {
+		c := ECDSASelfSignedCert()
+		c.Rand = mathrand.New(mathrand.NewSource(1)) //nolint:gosec // deterministic test
+		cert, err := c.Gen()
+		if err != nil {
+			t.Fatalf("ECDSASelfSignedCert.Gen() error %s", err)
+		}
+		x, err := x509.ParseCertificate(cert.Certificate[0])
+		if err != nil {
+			t.Fatalf("x509.ParseCertificate() error %s", err)
+		}
+		return x.SerialNumber
+	}
+
+	if a, b := serial(), serial(); a.Cmp(b) != 0 {
+		t.Fatalf("expected equal serial numbers, got %s and %s", a, b)
+	}
+}
+
+func TestSelfSignedCertValidity(t *testing.T) {
+	c := ECDSASelfSignedCert()
+	c.Hosts = []string{"127.0.0.1"}
+	c.ValidFrom = time.Now().Add(-2 * time.Hour)
+	c.ValidFor = time.Hour
+
+	cert, err := c.Gen()
+	if err != nil {
+		t.Fatalf("ECDSASelfSignedCert.Gen() error %s", err)
+	}
+	if err := getTLS(&cert, nil, ""); err == nil || !strings.Contains(err.Error(), "expired") {
+		t.Fatalf("http.Get() expected certificate expired error, got %v", err)
+	}
+
+	c.ValidFor = -time.Hour
+	if _, err := c.Gen(); err == nil {
+		t.Fatal("ECDSASelfSignedCert.Gen() expected error for negative validity period")
+	}
+}
+
 func testCert(t *testing.T, cert *tls.Certificate) { //nolint:thelper // this is not a test helper
+	if err := getTLS(cert, nil, ""); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// getTLS serves cert and connects to the server verifying the certificate with root as the only root,
+// if root is nil the certificate itself is used.
+func getTLS(cert *tls.Certificate, root *x509.Certificate, serverName string) error {
 	s := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	}))
@@ -48,22 +208,28 @@ func testCert(t *testing.T, cert *tls.Certificate) { //nolint:thelper // this is
 	defer s.Close()
 	s.StartTLS()
 
-	cacert, err := x509.ParseCertificate(cert.Certificate[0])
-	if err != nil {
-		t.Fatalf("x509.ParseCertificate() error %s", err)
+	if root == nil {
+		var err error
+		root, err = x509.ParseCertificate(cert.Certificate[0])
+		if err != nil {
+			return fmt.Errorf("x509.ParseCertificate() error %w", err)
+		}
 	}
 
 	pool := x509.NewCertPool()
-	pool.AddCert(cacert)
+	pool.AddCert(root)
 
 	c := http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
-		RootCAs: pool,
+		RootCAs:    pool,
+		ServerName: serverName,
 	}}}
 	resp, err := c.Get(s.URL)
 	if err != nil {
-		t.Fatalf("http.Get() error %s", err)
+		return fmt.Errorf("http.Get() error %w", err)
 	}
+	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
-		t.Fatalf("http.Get() status code %d", resp.StatusCode)
+		return fmt.Errorf("http.Get() status code %d", resp.StatusCode)
 	}
+	return nil
 }
@@ -0,0 +1,66 @@
+// Copyright 2022-2024 Sauce Labs Inc., all rights reserved.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package certutil
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// SaveCertPEM writes the certificate chain and the private key to PEM encoded files.
+// The private key is written in PKCS #8 form to a file only readable by the owner.
+func SaveCertPEM(cert tls.Certificate, certPath, keyPath string) error {
+	if len(cert.Certificate) == 0 {
+		return errors.New("certificate is empty")
+	}
+
+	var certBuf bytes.Buffer
+	for _, der := range cert.Certificate {
+		if err := pem.Encode(&certBuf, &pem.Block{Type: "CERTIFICATE", Bytes: der}); err != nil {
+			return fmt.Errorf("encode certificate %w", err)
+		}
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(cert.PrivateKey)
+	if err != nil {
+		return fmt.Errorf("marshal private key %w", err)
+	}
+	keyBuf := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+
+	if err := os.WriteFile(certPath, certBuf.Bytes(), 0o644); err != nil { //nolint:gosec // certificate is public
+		return fmt.Errorf("write certificate %w", err)
+	}
+	if err := os.WriteFile(keyPath, keyBuf, 0o600); err != nil {
+		return fmt.Errorf("write private key %w", err)
+	}
+
+	return nil
+}
+
+// LoadCertPEM reads a certificate saved with SaveCertPEM.
+// It returns an error if the private key does not match the certificate.
+func LoadCertPEM(certPath, keyPath string) (tls.Certificate, error) {
+	certPEM, err := os.ReadFile(certPath)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("read certificate %w", err)
+	}
+	keyPEM, err := os.ReadFile(keyPath)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("read private key %w", err)
+	}
+
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("load %s and %s: %w", certPath, keyPath, err)
+	}
+	return cert, nil
+}
@@ -0,0 +1,53 @@
+// Copyright 2022-2024 Sauce Labs Inc., all rights reserved.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+//go:build !windows
+
+package certutil
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSaveLoadCertPEM(t *testing.T) {
+	dir := t.TempDir()
+	certPath := filepath.Join(dir, "cert.pem")
+	keyPath := filepath.Join(dir, "key.pem")
+
+	c := ECDSASelfSignedCert()
+	c.Hosts = []string{"127.0.0.1"}
+	cert, err := c.Gen()
+	if err != nil {
+		t.Fatalf("ECDSASelfSignedCert.Gen() error %s", err)
+	}
+
+	if err := SaveCertPEM(cert, certPath, keyPath); err != nil {
+		t.Fatalf("SaveCertPEM() error %s", err)
+	}
+	loaded, err := LoadCertPEM(certPath, keyPath)
+	if err != nil {
+		t.Fatalf("LoadCertPEM() error %s", err)
+	}
+	testCert(t, &loaded)
+
+	other, err := c.Gen()
+	if err != nil {
+		t.Fatalf("ECDSASelfSignedCert.Gen() error %s", err)
+	}
+	otherKeyPath := filepath.Join(dir, "other-key.pem")
+	if err := SaveCertPEM(other, filepath.Join(dir, "other-cert.pem"), otherKeyPath); err != nil {
+		t.Fatalf("SaveCertPEM() error %s", err)
+	}
+	if _, err := LoadCertPEM(certPath, otherKeyPath); err == nil || !strings.Contains(err.Error(), "does not match") {
+		t.Fatalf("LoadCertPEM() expected key mismatch error, got %v", err)
+	}
+
+	if _, err := LoadCertPEM(filepath.Join(dir, "missing.pem"), keyPath); err == nil || !strings.Contains(err.Error(), "read certificate") {
+		t.Fatalf("LoadCertPEM() expected missing file error, got %v", err)
+	}
+}
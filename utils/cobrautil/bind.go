@@ -9,6 +9,7 @@ package cobrautil
 import (
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/spf13/cast"
@@ -48,12 +49,35 @@ func BindAll(cmd *cobra.Command, envPrefix, configFileFlagName string) error {
 			if err := v.ReadInConfig(); err != nil {
 				return err
 			}
+			if err := checkUnknownKeys(cmd, v); err != nil {
+				return fmt.Errorf("config file %s: %w", f, err)
+			}
 		}
 	}
 
 	return BindFromViper(cmd, v)
 }
 
+// checkUnknownKeys returns an error if the config file contains keys that do not match any flag.
+// This prevents typos in the config file from being silently ignored.
+func checkUnknownKeys(cmd *cobra.Command, v *viper.Viper) error {
+	var unknown []string
+	for _, k := range v.AllKeys() {
+		if !v.InConfig(k) {
+			continue
+		}
+		if cmd.Flags().Lookup(k) == nil && cmd.InheritedFlags().Lookup(k) == nil {
+			unknown = append(unknown, k)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("unknown keys: %s", strings.Join(unknown, ", "))
+	}
+
+	return nil
+}
+
 // BindFromViper updates the given command flags with values from preconditioned Viper instance.
 func BindFromViper(cmd *cobra.Command, v *viper.Viper) error {
 	// Update cobra flags with values from viper
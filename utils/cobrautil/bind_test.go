@@ -8,6 +8,7 @@ package cobrautil
 
 import (
 	"net/netip"
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -64,3 +65,20 @@ func TestBindSlice(t *testing.T) {
 		})
 	}
 }
+
+func TestBindUnknownKeys(t *testing.T) {
+	cmd := &cobra.Command{}
+	fs := cmd.Flags()
+
+	var v testSliceStruct
+	fs.String("config-file", "testdata/bind-unknown.yaml", "")
+	fs.StringSliceVar(&v.Strings, "strings", nil, "")
+
+	err := BindAll(cmd, "TEST", "config-file")
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if !strings.Contains(err.Error(), "unknown keys: strngs") {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
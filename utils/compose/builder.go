@@ -7,6 +7,7 @@
 package compose
 
 import (
+	"fmt"
 	"os"
 	"path"
 	"strings"
@@ -23,6 +24,8 @@ func (s *Service) Service() *Service {
 type Builder struct {
 	c     *Compose
 	error error
+
+	skipEnvFileCheck bool
 }
 
 func NewBuilder() *Builder {
@@ -31,6 +34,14 @@ func NewBuilder() *Builder {
 	}
 }
 
+// SkipEnvFileCheck disables checking that env files of services added later exist,
+// i.e. when compose runs on a remote host that has the files.
+// Relative env file paths are still made absolute.
+func (b *Builder) SkipEnvFileCheck() *Builder {
+	b.skipEnvFileCheck = true
+	return b
+}
+
 func (b *Builder) AddService(sb ServiceBuilder) *Builder {
 	if b.error != nil {
 		return b
@@ -41,6 +52,22 @@ func (b *Builder) AddService(sb ServiceBuilder) *Builder {
 	for i, v := range s.Volumes {
 		s.Volumes[i] = absVolume(v)
 	}
+	if s.Build != nil && s.Build.Context != "" && !path.IsAbs(s.Build.Context) {
+		s.Build.Context = path.Join(curDir(), s.Build.Context)
+	}
+	for i, f := range s.EnvFile {
+		if !path.IsAbs(f) {
+			f = path.Join(curDir(), f)
+			s.EnvFile[i] = f
+		}
+		if b.skipEnvFileCheck {
+			continue
+		}
+		if _, err := os.Stat(f); err != nil {
+			b.error = fmt.Errorf("service %s env file: %w", s.Name, err)
+			return b
+		}
+	}
 
 	b.error = b.c.AddService(s)
 
@@ -49,7 +76,7 @@ func (b *Builder) AddService(sb ServiceBuilder) *Builder {
 
 func absVolume(v string) string {
 	a := strings.Split(v, ":")
-	if path.IsAbs(a[0]) {
+	if len(a) == 1 || path.IsAbs(a[0]) || isVolumeName(a[0]) {
 		return v
 	}
 
@@ -79,6 +106,9 @@ func (b *Builder) Build() (*Compose, error) {
 	if b.error != nil {
 		return nil, b.error
 	}
+	if err := b.c.Validate(); err != nil {
+		return nil, err
+	}
 	return b.c, nil
 }
 
@@ -8,18 +8,54 @@ package compose
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
 	"path"
+	"path/filepath"
 	"slices"
+	"strconv"
 	"strings"
 	"time"
 )
 
+// Runner runs a command, it allows to replace os/exec in tests.
+type Runner interface {
+	Run(cmd *exec.Cmd) error
+}
+
+type execRunner struct{}
+
+func (execRunner) Run(cmd *exec.Cmd) error {
+	return cmd.Run()
+}
+
 type Command struct {
-	rt     string
+	// Runner runs the commands, it defaults to os/exec.
+	Runner Runner
+
+	// DockerBinary is the container runtime binary used to run "compose" and "inspect" subcommands.
+	// It defaults to the CONTAINER_RUNTIME environment variable or "docker".
+	DockerBinary string
+	// ComposeBinary is a standalone compose binary i.e. docker-compose, if set it is used instead of "<DockerBinary> compose".
+	// It defaults to the COMPOSE_BINARY environment variable.
+	ComposeBinary string
+
+	// ExtraFiles are compose files merged in order on top of the generated compose file.
+	// Compose runs in the command directory, so Up makes relative paths absolute
+	// using the current working directory.
+	ExtraFiles []string
+
+	// UpRetries is the number of times Up is retried when it fails with a transient error,
+	// i.e. when the container runtime daemon cannot be reached.
+	// Other errors are returned immediately.
+	UpRetries int
+	// UpRetryBackoff is the time to wait before the first retry, it doubles after each retry.
+	UpRetryBackoff time.Duration
+
+	c      *Compose
 	sep    string
 	dir    string
 	stdout io.Writer
@@ -32,11 +68,6 @@ func NewCommand(c *Compose, dir string, stdout, stderr io.Writer) (*Command, err
 		rt = "docker"
 	}
 
-	sep := "_"
-	if rt == "docker" || hasDockerCompose() {
-		sep = "-"
-	}
-
 	if dir == "" {
 		d, err := os.MkdirTemp("", "compose-*")
 		if err != nil {
@@ -66,16 +97,19 @@ func NewCommand(c *Compose, dir string, stdout, stderr io.Writer) (*Command, err
 	}
 
 	return &Command{
-		rt:     rt,
-		sep:    sep,
-		dir:    dir,
-		stdout: stdout,
-		stderr: stderr,
+		Runner:        execRunner{},
+		DockerBinary:  rt,
+		ComposeBinary: os.Getenv("COMPOSE_BINARY"),
+		c:             c,
+		dir:           dir,
+		stdout:        stdout,
+		stderr:        stderr,
 	}, nil
 }
 
+// Runtime returns the container runtime name i.e. docker or podman, it is the base name of DockerBinary.
 func (c *Command) Runtime() string {
-	return c.rt
+	return strings.TrimSuffix(filepath.Base(c.DockerBinary), ".exe")
 }
 
 func (c *Command) Project() string {
@@ -95,23 +129,173 @@ func (c *Command) Close() error {
 }
 
 func (c *Command) Up(args ...string) error {
-	if slices.ContainsFunc(args, func(s string) bool { return s == "-d" || s == "--detach" }) {
-		return c.quietRun(c.cmd("up", args))
+	return c.UpContext(context.Background(), args...)
+}
+
+// UpContext is like Up but the command is killed when the context is done.
+func (c *Command) UpContext(ctx context.Context, args ...string) error {
+	files := make([]string, len(c.ExtraFiles))
+	for i, f := range c.ExtraFiles {
+		f, err := filepath.Abs(f)
+		if err != nil {
+			return fmt.Errorf("extra compose file: %w", err)
+		}
+		if _, err := os.Stat(f); err != nil {
+			return fmt.Errorf("extra compose file: %w", err)
+		}
+		files[i] = f
+	}
+	c.ExtraFiles = files
+
+	detach := slices.ContainsFunc(args, func(s string) bool { return s == "-d" || s == "--detach" })
+
+	backoff := c.UpRetryBackoff
+	for i := 0; ; i++ {
+		stderr, err := c.up(ctx, args, detach)
+		if err == nil || i >= c.UpRetries || !isTransientError(stderr) {
+			return err
+		}
+
+		fmt.Fprintf(c.stderr, "compose up failed with transient error, retrying in %s (%d/%d)\n", backoff, i+1, c.UpRetries)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
 	}
+}
+
+// up runs compose up and returns its stderr, if detach is set the output is written only on error.
+func (c *Command) up(ctx context.Context, args []string, detach bool) (string, error) {
+	cmd := c.cmd(ctx, "up", args)
+
+	var stdout, stderr bytes.Buffer
+	if detach {
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+	} else {
+		cmd.Stdout = c.stdout
+		cmd.Stderr = io.MultiWriter(c.stderr, &stderr)
+	}
+
+	err := c.Runner.Run(cmd)
+	if err != nil && detach {
+		stdout.WriteTo(c.stdout)
+		c.stderr.Write(stderr.Bytes())
+	}
+	return stderr.String(), err
+}
+
+var transientErrors = []string{
+	"error during connect",
+	"Cannot connect to the Docker daemon",
+	"connection reset by peer",
+	"TLS handshake timeout",
+	"i/o timeout",
+}
 
-	return c.run(c.cmd("up", args))
+func isTransientError(stderr string) bool {
+	return slices.ContainsFunc(transientErrors, func(s string) bool {
+		return strings.Contains(stderr, s)
+	})
 }
 
+// Down stops and removes the containers.
+// It does not take a context, so that teardown can run after the context passed to UpContext is canceled.
 func (c *Command) Down(args ...string) error {
-	return c.quietRun(c.cmd("down", args))
+	return c.quietRun(c.cmd(context.Background(), "down", args))
 }
 
 func (c *Command) Ps(args ...string) error {
-	return c.run(c.cmd("ps", args))
+	return c.run(c.cmd(context.Background(), "ps", args))
 }
 
 func (c *Command) Logs(args ...string) error {
-	return c.run(c.cmd("logs", args))
+	return c.run(c.cmd(context.Background(), "logs", args))
+}
+
+// Port returns the public host:port bound to the private port of the service.
+// The private port may have a protocol suffix i.e. 53/udp.
+func (c *Command) Port(service, port string) (string, error) {
+	var args []string
+	if p, proto, ok := strings.Cut(port, "/"); ok {
+		args = append(args, "--protocol", proto)
+		port = p
+	}
+	args = append(args, service, port)
+
+	cmd := c.cmd(context.Background(), "port", args)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := c.Runner.Run(cmd); err != nil {
+		return "", fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	hp := strings.TrimSpace(stdout.String())
+	if hp == "" || hp == ":0" {
+		return "", fmt.Errorf("port %s of service %s is not published", port, service)
+	}
+	return hp, nil
+}
+
+// Ports returns a map of container ports to the public host:port for all ports published by the service.
+// This is useful when the host port is allocated by the runtime i.e. "0:8080".
+// Port ranges are expanded, each container port in the range is a separate key i.e. "10000/udp".
+func (c *Command) Ports(service string) (map[string]string, error) {
+	s := c.c.Services[service]
+	if s == nil {
+		return nil, fmt.Errorf("unknown service %s", service)
+	}
+
+	m := make(map[string]string, len(s.Ports))
+	for _, p := range s.Ports {
+		cps, err := containerPorts(p)
+		if err != nil {
+			return nil, fmt.Errorf("service %s port %q: %w", service, p, err)
+		}
+		for _, cp := range cps {
+			hp, err := c.Port(service, cp)
+			if err != nil {
+				return nil, err
+			}
+			m[cp] = hp
+		}
+	}
+	return m, nil
+}
+
+// containerPorts returns the container ports of a published port in the short syntax [[ip:]host:]container[/protocol].
+// Port ranges i.e. 10000-10010/udp are expanded to individual ports, the protocol suffix is kept.
+func containerPorts(p string) ([]string, error) {
+	p, proto, hasProto := strings.Cut(p, "/")
+	if i := strings.LastIndex(p, ":"); i != -1 {
+		p = p[i+1:]
+	}
+
+	from, to, ok := strings.Cut(p, "-")
+	if !ok {
+		to = from
+	}
+	start, err := strconv.ParseUint(from, 10, 16)
+	if err != nil || start == 0 {
+		return nil, fmt.Errorf("invalid container port %q", p)
+	}
+	end, err := strconv.ParseUint(to, 10, 16)
+	if err != nil || end < start {
+		return nil, fmt.Errorf("invalid container port range %q", p)
+	}
+
+	ports := make([]string, 0, end-start+1)
+	for i := start; i <= end; i++ {
+		cp := strconv.FormatUint(i, 10)
+		if hasProto {
+			cp += "/" + proto
+		}
+		ports = append(ports, cp)
+	}
+	return ports, nil
 }
 
 const healthy = "healthy"
@@ -126,6 +310,11 @@ func (sh serviceHealth) String() string {
 }
 
 func (c *Command) Wait(interval, timeout time.Duration, services []string) error {
+	return c.WaitContext(context.Background(), interval, timeout, services)
+}
+
+// WaitContext is like Wait but it returns early with the context error when the context is done.
+func (c *Command) WaitContext(ctx context.Context, interval, timeout time.Duration, services []string) error {
 	to := time.NewTimer(timeout)
 	defer to.Stop()
 	t := time.NewTicker(interval)
@@ -135,12 +324,14 @@ func (c *Command) Wait(interval, timeout time.Duration, services []string) error
 
 	for {
 		select {
+		case <-ctx.Done():
+			return ctx.Err()
 		case <-to.C:
 			return fmt.Errorf("timeout waiting for services to be healthy: %v", lastStatus)
 		case <-t.C:
 			n := 0
 			for i, s := range services {
-				h := c.serviceHealth(s)
+				h := c.serviceHealth(ctx, s)
 				if h == "" || h == healthy {
 					n++
 				}
@@ -153,7 +344,7 @@ func (c *Command) Wait(interval, timeout time.Duration, services []string) error
 	}
 }
 
-func (c *Command) serviceHealth(s string) string {
+func (c *Command) serviceHealth(ctx context.Context, s string) string {
 	args := []string{
 		"inspect",
 		"--format",
@@ -161,12 +352,12 @@ func (c *Command) serviceHealth(s string) string {
 		c.serviceContainerName(s),
 	}
 
-	cmd := exec.Command(c.rt, args...) //nolint:gosec // this is a command runner
+	cmd := exec.CommandContext(ctx, c.DockerBinary, args...) //nolint:gosec // this is a command runner
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
-	if err := cmd.Run(); err != nil {
+	if err := c.Runner.Run(cmd); err != nil {
 		if bytes.Contains(stderr.Bytes(), []byte("no such object")) {
 			return "no such object"
 		}
@@ -182,17 +373,70 @@ func (c *Command) serviceHealth(s string) string {
 }
 
 func (c *Command) serviceContainerName(s string) string {
-	return fmt.Sprintf("%s%s%s%s1", c.Project(), c.sep, s, c.sep)
+	if svc := c.c.Services[s]; svc != nil && svc.ContainerName != "" {
+		return svc.ContainerName
+	}
+
+	sep := c.separator()
+	return fmt.Sprintf("%s%s%s%s1", c.Project(), sep, s, sep)
+}
+
+// separator returns the separator used in container names, compose v1 uses "_" and v2 uses "-".
+// The result is cached, the compose binary must not be changed after the first call.
+func (c *Command) separator() string {
+	if c.sep != "" {
+		return c.sep
+	}
+
+	switch {
+	case c.ComposeBinary != "":
+		c.sep = "-"
+		if c.composeMajorVersion() == "1" {
+			c.sep = "_"
+		}
+	case c.Runtime() == "docker" || hasDockerCompose():
+		c.sep = "-"
+	default:
+		c.sep = "_"
+	}
+	return c.sep
 }
 
-func (c *Command) cmd(subcmd string, args []string) *exec.Cmd {
+// composeMajorVersion returns the major version of the standalone compose binary or empty string if it cannot be determined.
+func (c *Command) composeMajorVersion() string {
+	cmd := exec.Command(c.ComposeBinary, "version", "--short") //nolint:gosec // this is a command runner
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = io.Discard
+	if err := c.Runner.Run(cmd); err != nil {
+		return ""
+	}
+
+	v := strings.TrimPrefix(strings.TrimSpace(stdout.String()), "v")
+	major, _, _ := strings.Cut(v, ".")
+	return major
+}
+
+func (c *Command) cmd(ctx context.Context, subcmd string, args []string) *exec.Cmd {
+	name := c.DockerBinary
 	allArgs := []string{
 		"compose",
 		subcmd,
 	}
+	if c.ComposeBinary != "" {
+		name = c.ComposeBinary
+		allArgs = allArgs[1:]
+	}
+	if len(c.ExtraFiles) > 0 {
+		files := []string{"-f", c.File()}
+		for _, f := range c.ExtraFiles {
+			files = append(files, "-f", f)
+		}
+		allArgs = slices.Insert(allArgs, len(allArgs)-1, files...)
+	}
 	allArgs = append(allArgs, args...)
 
-	cmd := exec.Command(c.rt, allArgs...) //nolint:gosec // this is a command runner
+	cmd := exec.CommandContext(ctx, name, allArgs...) //nolint:gosec // this is a command runner
 	cmd.Dir = c.dir
 
 	return cmd
@@ -203,7 +447,7 @@ func (c *Command) quietRun(cmd *exec.Cmd) error {
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
-	err := cmd.Run()
+	err := c.Runner.Run(cmd)
 	if err != nil {
 		stdout.WriteTo(c.stdout)
 		stderr.WriteTo(c.stderr)
@@ -214,7 +458,7 @@ func (c *Command) quietRun(cmd *exec.Cmd) error {
 func (c *Command) run(cmd *exec.Cmd) error {
 	cmd.Stdout = c.stdout
 	cmd.Stderr = c.stderr
-	return cmd.Run()
+	return c.Runner.Run(cmd)
 }
 
 func hasDockerCompose() bool {
@@ -0,0 +1,364 @@
+// Copyright 2022-2024 Sauce Labs Inc., all rights reserved.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package compose
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestCommand(t *testing.T) *Command {
+	t.Helper()
+
+	t.Setenv("CONTAINER_RUNTIME", "docker")
+	t.Setenv("COMPOSE_BINARY", "")
+
+	cmd, err := NewCommand(New(), t.TempDir(), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cmd
+}
+
+// fakeRunner writes the output configured for the command line to stdout, unknown commands fail.
+// Commands listed in fail write the next message to stderr and fail until the messages are used up.
+type fakeRunner struct {
+	out  map[string]string
+	fail map[string][]string
+	args []string
+}
+
+func (r *fakeRunner) Run(cmd *exec.Cmd) error {
+	args := strings.Join(cmd.Args, " ")
+	r.args = append(r.args, args)
+
+	if msgs := r.fail[args]; len(msgs) > 0 {
+		r.fail[args] = msgs[1:]
+		io.WriteString(cmd.Stderr, msgs[0])
+		return fmt.Errorf("exit status 1")
+	}
+
+	out, ok := r.out[args]
+	if !ok {
+		return fmt.Errorf("unexpected command: %s", args)
+	}
+	io.WriteString(cmd.Stdout, out)
+	return nil
+}
+
+func TestCommandBinary(t *testing.T) {
+	const inspect = "inspect --format {{.State.Health.Status}}"
+
+	tests := []struct {
+		name    string
+		docker  string
+		compose string
+		out     map[string]string
+	}{
+		{
+			name:   "docker",
+			docker: "/opt/bin/docker",
+			out: map[string]string{
+				"/opt/bin/docker compose up -d":                   "",
+				"/opt/bin/docker " + inspect + " project-proxy-1": "healthy",
+			},
+		},
+		{
+			name:    "standalone v1",
+			docker:  "docker",
+			compose: "docker-compose",
+			out: map[string]string{
+				"docker-compose version --short":         "1.29.2\n",
+				"docker-compose up -d":                   "",
+				"docker " + inspect + " project_proxy_1": "healthy",
+			},
+		},
+		{
+			name:    "standalone v2",
+			docker:  "docker",
+			compose: "/usr/local/bin/docker-compose",
+			out: map[string]string{
+				"/usr/local/bin/docker-compose version --short": "v2.29.1\n",
+				"/usr/local/bin/docker-compose up -d":           "",
+				"docker " + inspect + " project-proxy-1":        "healthy",
+			},
+		},
+	}
+
+	for i := range tests {
+		tc := &tests[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("COMPOSE_PROJECT_NAME", "project")
+
+			cmd := newTestCommand(t)
+			r := &fakeRunner{out: tc.out}
+			cmd.Runner = r
+			cmd.DockerBinary = tc.docker
+			cmd.ComposeBinary = tc.compose
+
+			if rt := cmd.Runtime(); rt != "docker" {
+				t.Fatalf("expected runtime docker, got %q", rt)
+			}
+			if err := cmd.Up("-d"); err != nil {
+				t.Fatal(err)
+			}
+			if err := cmd.Wait(time.Millisecond, time.Second, []string{"proxy"}); err != nil {
+				t.Fatalf("%v, commands: %v", err, r.args)
+			}
+		})
+	}
+}
+
+func TestCommandPorts(t *testing.T) {
+	tests := []struct {
+		name  string
+		ports []string
+		out   map[string]string
+		want  map[string]string
+		err   string
+	}{
+		{
+			name:  "single",
+			ports: []string{"8080", "0:9090", "127.0.0.1::53/udp", "[::1]:3128:3128"},
+			out: map[string]string{
+				"docker compose port svc 8080":              "0.0.0.0:32768\n",
+				"docker compose port svc 9090":              "0.0.0.0:32769\n",
+				"docker compose port --protocol udp svc 53": "127.0.0.1:32770\n",
+				"docker compose port svc 3128":              "[::1]:3128\n",
+			},
+			want: map[string]string{
+				"8080":   "0.0.0.0:32768",
+				"9090":   "0.0.0.0:32769",
+				"53/udp": "127.0.0.1:32770",
+				"3128":   "[::1]:3128",
+			},
+		},
+		{
+			name:  "range",
+			ports: []string{"10000-10001:10000-10001/udp", "127.0.0.1:5000-5001:6000-6001"},
+			out: map[string]string{
+				"docker compose port --protocol udp svc 10000": "0.0.0.0:10000\n",
+				"docker compose port --protocol udp svc 10001": "0.0.0.0:10001\n",
+				"docker compose port svc 6000":                 "127.0.0.1:5000\n",
+				"docker compose port svc 6001":                 "127.0.0.1:5001\n",
+			},
+			want: map[string]string{
+				"10000/udp": "0.0.0.0:10000",
+				"10001/udp": "0.0.0.0:10001",
+				"6000":      "127.0.0.1:5000",
+				"6001":      "127.0.0.1:5001",
+			},
+		},
+		{
+			name:  "not published",
+			ports: []string{"8080"},
+			out: map[string]string{
+				"docker compose port svc 8080": ":0\n",
+			},
+			err: "port 8080 of service svc is not published",
+		},
+	}
+
+	for i := range tests {
+		tc := &tests[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("CONTAINER_RUNTIME", "docker")
+			t.Setenv("COMPOSE_BINARY", "")
+
+			c, err := NewBuilder().AddService(&Service{Name: "svc", Image: "img", Ports: tc.ports}).Build()
+			if err != nil {
+				t.Fatal(err)
+			}
+			cmd, err := NewCommand(c, t.TempDir(), nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cmd.Runner = &fakeRunner{out: tc.out}
+
+			got, err := cmd.Ports("svc")
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("expected error to contain %q, got %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !maps.Equal(got, tc.want) {
+				t.Fatalf("expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestContainerPorts(t *testing.T) {
+	tests := []struct {
+		port string
+		want []string
+		err  string
+	}{
+		{port: "8080", want: []string{"8080"}},
+		{port: "0:8080/tcp", want: []string{"8080/tcp"}},
+		{port: "[::1]::8080", want: []string{"8080"}},
+		{port: "127.0.0.1:9000-9002:9000-9002/udp", want: []string{"9000/udp", "9001/udp", "9002/udp"}},
+		{port: "9002-9000", err: `invalid container port range "9002-9000"`},
+		{port: "9000-x", err: `invalid container port range "9000-x"`},
+		{port: "0", err: `invalid container port "0"`},
+	}
+
+	for i := range tests {
+		tc := &tests[i]
+		t.Run(tc.port, func(t *testing.T) {
+			got, err := containerPorts(tc.port)
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("expected error to contain %q, got %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, tc.want) {
+				t.Fatalf("expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestCommandExtraFiles(t *testing.T) {
+	cmd := newTestCommand(t)
+	cmd.ExtraFiles = []string{"compose.ci.yaml", "compose.local.yaml"}
+
+	got := cmd.cmd(context.Background(), "up", []string{"-d"}).Args
+	want := []string{
+		"docker", "compose",
+		"-f", cmd.File(),
+		"-f", "compose.ci.yaml",
+		"-f", "compose.local.yaml",
+		"up", "-d",
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+
+	if err := cmd.Up("-d"); err == nil {
+		t.Fatal("expected error for missing extra compose file")
+	}
+}
+
+func TestCommandExtraFilesRelative(t *testing.T) {
+	cmd := newTestCommand(t)
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "compose.ci.yaml"), []byte("services: {}\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	chdir(t, dir)
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	abs := filepath.Join(wd, "compose.ci.yaml")
+
+	up := strings.Join([]string{"docker", "compose", "-f", cmd.File(), "-f", abs, "up", "-d"}, " ")
+	r := &fakeRunner{out: map[string]string{up: ""}}
+	cmd.Runner = r
+	cmd.ExtraFiles = []string{"compose.ci.yaml"}
+
+	if err := cmd.Up("-d"); err != nil {
+		t.Fatalf("%v, commands: %v", err, r.args)
+	}
+}
+
+// chdir changes the working directory for the duration of the test, testing.T.Chdir is not available in Go 1.21.
+// Tests using it must not run in parallel, as the working directory is process-wide.
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := os.Chdir(wd); err != nil {
+			t.Errorf("restore working directory: %v", err)
+		}
+	})
+}
+
+func TestCommandUpRetry(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     string
+		retries int
+		calls   int
+		success bool
+	}{
+		{
+			name:    "transient error",
+			err:     "error during connect: Post http://docker/v1.45/containers/create: EOF",
+			retries: 3,
+			calls:   3,
+			success: true,
+		},
+		{
+			name:    "transient error retries exhausted",
+			err:     "error during connect: Post http://docker/v1.45/containers/create: EOF",
+			retries: 1,
+			calls:   2,
+		},
+		{
+			name:    "non transient error",
+			err:     "no such service: foo",
+			retries: 3,
+			calls:   1,
+		},
+	}
+
+	for i := range tests {
+		tc := &tests[i]
+		t.Run(tc.name, func(t *testing.T) {
+			cmd := newTestCommand(t)
+			cmd.stdout = io.Discard
+			cmd.stderr = io.Discard
+			cmd.UpRetries = tc.retries
+			cmd.UpRetryBackoff = time.Millisecond
+
+			const up = "docker compose up -d"
+			r := &fakeRunner{
+				out:  map[string]string{up: ""},
+				fail: map[string][]string{up: {tc.err, tc.err}},
+			}
+			cmd.Runner = r
+
+			err := cmd.Up("-d")
+			if tc.success && err != nil {
+				t.Fatalf("expected success, got %v", err)
+			}
+			if !tc.success && err == nil {
+				t.Fatal("expected error")
+			}
+			if n := len(r.args); n != tc.calls {
+				t.Fatalf("expected %d calls, got %d", tc.calls, n)
+			}
+		})
+	}
+}
@@ -9,11 +9,14 @@ package compose
 import (
 	"fmt"
 	"io"
+	"os"
 
 	"gopkg.in/yaml.v3"
 )
 
 type Compose struct {
+	// Version is obsolete and ignored by compose, it is kept so that loaded files are written back unchanged.
+	Version  string              `yaml:"version,omitempty"`
 	Services map[string]*Service `yaml:"services,omitempty"`
 	Networks map[string]*Network `yaml:"networks,omitempty"`
 }
@@ -25,6 +28,65 @@ func New() *Compose {
 	}
 }
 
+// Load reads a compose file in YAML format.
+// Only the fields modeled by Compose, Service and Network are supported, unknown fields result in an error.
+// Service names, and network names if not specified, are set from the map keys.
+// Dependencies are checked and their default conditions are set as in AddService.
+func Load(r io.Reader) (*Compose, error) {
+	c := New()
+
+	dec := yaml.NewDecoder(r)
+	dec.KnownFields(true)
+	if err := dec.Decode(c); err != nil {
+		return nil, err
+	}
+
+	for name, s := range c.Services {
+		if s == nil {
+			return nil, fmt.Errorf("service %s is empty", name)
+		}
+		s.Name = name
+		if err := s.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	for _, s := range c.Services {
+		deps, err := c.dependsOn(s)
+		if err != nil {
+			return nil, err
+		}
+		s.DependsOn = deps
+	}
+	for name, n := range c.Networks {
+		if n == nil {
+			return nil, fmt.Errorf("network %s is empty", name)
+		}
+		if n.Name == "" {
+			n.Name = name
+		}
+		if err := n.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// LoadFile is like Load but reads the compose file from path.
+func LoadFile(path string) (*Compose, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return Load(f)
+}
+
 func (c *Compose) AddService(s *Service) error {
 	if err := s.Validate(); err != nil {
 		return err
@@ -32,12 +94,41 @@ func (c *Compose) AddService(s *Service) error {
 	if c.Services[s.Name] != nil {
 		return fmt.Errorf("service %s already exists", s.Name)
 	}
+	deps, err := c.dependsOn(s)
+	if err != nil {
+		return err
+	}
+	s.DependsOn = deps
 
 	c.Services[s.Name] = s
 
 	return nil
 }
 
+// dependsOn returns a copy of the service dependencies with default conditions set,
+// the map is copied so that a map shared by the caller is not modified.
+func (c *Compose) dependsOn(s *Service) (map[string]Dependency, error) {
+	if s.DependsOn == nil {
+		return nil, nil
+	}
+
+	deps := make(map[string]Dependency, len(s.DependsOn))
+	for name, d := range s.DependsOn {
+		dep := c.Services[name]
+		if dep == nil {
+			return nil, fmt.Errorf("service %s depends on unknown service %s", s.Name, name)
+		}
+		if d.Condition == "" {
+			d.Condition = ServiceStarted
+			if dep.HealthCheck != nil {
+				d.Condition = ServiceHealthy
+			}
+		}
+		deps[name] = d
+	}
+	return deps, nil
+}
+
 func (c *Compose) AddNetwork(n *Network) error {
 	if err := n.Validate(); err != nil {
 		return err
@@ -51,8 +142,29 @@ func (c *Compose) AddNetwork(n *Network) error {
 	return nil
 }
 
+// Validate checks references between services and networks.
+// Networks may be added after the services that use them, so this is not done in AddService.
+func (c *Compose) Validate() error {
+	for _, s := range c.Services {
+		for name := range s.Network {
+			if name == "default" {
+				continue
+			}
+			if c.Networks[name] == nil {
+				return fmt.Errorf("service %s uses undeclared network %s", s.Name, name)
+			}
+		}
+	}
+	return nil
+}
+
+// Render returns the compose file in YAML format.
+func (c *Compose) Render() ([]byte, error) {
+	return yaml.Marshal(c)
+}
+
 func (c *Compose) WriteTo(w io.Writer) (int, error) {
-	b, err := yaml.Marshal(c)
+	b, err := c.Render()
 	if err != nil {
 		return 0, err
 	}
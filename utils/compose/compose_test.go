@@ -0,0 +1,630 @@
+// Copyright 2022-2024 Sauce Labs Inc., all rights reserved.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package compose
+
+import (
+	"bytes"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestComposeDependsOn(t *testing.T) {
+	c := New()
+	if err := c.AddService(&Service{
+		Name:  "upstream",
+		Image: "upstream",
+		HealthCheck: &HealthCheck{
+			Test: []string{"CMD", "true"},
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	deps := map[string]Dependency{"upstream": {}}
+	if err := c.AddService(&Service{
+		Name:      "proxy",
+		Image:     "proxy",
+		DependsOn: deps,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if d := deps["upstream"]; d.Condition != "" {
+		t.Fatalf("expected caller map to be unchanged, got condition %q", d.Condition)
+	}
+
+	b, err := c.Render()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "depends_on:\n            upstream:\n                condition: service_healthy\n"; !strings.Contains(string(b), want) {
+		t.Fatalf("expected %q in:\n%s", want, b)
+	}
+
+	err = c.AddService(&Service{
+		Name:      "client",
+		Image:     "client",
+		DependsOn: map[string]Dependency{"missing": {}},
+	})
+	if err == nil || !strings.Contains(err.Error(), "unknown service missing") {
+		t.Fatalf("expected unknown service error, got %v", err)
+	}
+}
+
+func TestServiceValidateHealthCheck(t *testing.T) {
+	s := &Service{
+		Name:        "svc",
+		Image:       "img",
+		HealthCheck: &HealthCheck{},
+	}
+	if err := s.Validate(); err == nil || !strings.Contains(err.Error(), "test is empty") {
+		t.Fatalf("expected test is empty error, got %v", err)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	const in = `services:
+    proxy:
+        image: proxy
+        ports:
+            - "3128:3128"
+        healthcheck:
+            test: ["CMD", "true"]
+            interval: 5s
+    upstream:
+        image: upstream
+`
+	c, err := Load(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Services) != 2 {
+		t.Fatalf("expected 2 services, got %d", len(c.Services))
+	}
+	if s := c.Services["proxy"]; s.Name != "proxy" || s.HealthCheck.Interval.String() != "5s" {
+		t.Fatalf("unexpected service %+v", s)
+	}
+
+	var b bytes.Buffer
+	if _, err := c.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	rc, err := Load(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rc.Services) != 2 || rc.Services["upstream"].Image != "upstream" {
+		t.Fatalf("unexpected services after round trip %v", rc.Services)
+	}
+
+	if _, err := Load(strings.NewReader("services:\n    proxy:\n        image: proxy\n        foo: bar\n")); err == nil {
+		t.Fatal("expected error for unknown field")
+	}
+}
+
+func TestLoadComposeFields(t *testing.T) {
+	const in = `version: "3.8"
+services:
+    proxy:
+        container_name: forwarder
+        image: proxy
+        command: ["run", "--address", ":3128"]
+        depends_on:
+            - upstream
+    upstream:
+        image: upstream
+        command: serve --port 80
+        healthcheck:
+            test: ["CMD", "true"]
+    client:
+        image: client
+        depends_on:
+            proxy:
+                condition: service_started
+`
+	c, err := Load(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Version != "3.8" {
+		t.Fatalf("expected version 3.8, got %q", c.Version)
+	}
+
+	p := c.Services["proxy"]
+	if p.ContainerName != "forwarder" {
+		t.Fatalf("expected container name forwarder, got %q", p.ContainerName)
+	}
+	if want := []string{"run", "--address", ":3128"}; !slices.Equal(p.CommandArgs, want) || p.Command != "" {
+		t.Fatalf("expected command args %v, got %q %v", want, p.Command, p.CommandArgs)
+	}
+	if d := p.DependsOn["upstream"]; d.Condition != ServiceHealthy {
+		t.Fatalf("expected upstream condition %s, got %q", ServiceHealthy, d.Condition)
+	}
+	if u := c.Services["upstream"]; u.Command != "serve --port 80" || len(u.CommandArgs) != 0 {
+		t.Fatalf("unexpected upstream command %q %v", u.Command, u.CommandArgs)
+	}
+	if d := c.Services["client"].DependsOn["proxy"]; d.Condition != ServiceStarted {
+		t.Fatalf("expected proxy condition %s, got %q", ServiceStarted, d.Condition)
+	}
+
+	var b bytes.Buffer
+	if _, err := c.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	rc, err := Load(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rc.Version != c.Version || !slices.Equal(rc.Services["proxy"].CommandArgs, p.CommandArgs) {
+		t.Fatalf("unexpected compose after round trip:\n%s", b.String())
+	}
+}
+
+func TestLoadSyntaxForms(t *testing.T) {
+	tests := []struct {
+		name  string
+		in    string
+		top   string
+		check func(t *testing.T, s *Service)
+	}{
+		{
+			name: "environment list",
+			in:   "environment:\n    - FOO=bar\n    - EMPTY=\n    - URL=http://host/?a=b\n",
+			check: func(t *testing.T, s *Service) {
+				t.Helper()
+				want := map[string]string{"FOO": "bar", "EMPTY": "", "URL": "http://host/?a=b"}
+				if !maps.Equal(s.Environment, want) {
+					t.Fatalf("expected environment %v, got %v", want, s.Environment)
+				}
+			},
+		},
+		{
+			name: "networks list",
+			in:   "networks:\n    - front\n    - back\n",
+			top:  "networks:\n    front:\n        driver: bridge\n    back:\n        driver: bridge\n",
+			check: func(t *testing.T, s *Service) {
+				t.Helper()
+				want := map[string]ServiceNetwork{"front": {}, "back": {}}
+				if !maps.Equal(s.Network, want) {
+					t.Fatalf("expected networks %v, got %v", want, s.Network)
+				}
+			},
+		},
+		{
+			name: "healthcheck test string",
+			in:   "healthcheck:\n    test: curl -f http://localhost || exit 1\n    retries: 5\n",
+			check: func(t *testing.T, s *Service) {
+				t.Helper()
+				want := []string{"CMD-SHELL", "curl -f http://localhost || exit 1"}
+				if !slices.Equal(s.HealthCheck.Test, want) || s.HealthCheck.Retries != 5 {
+					t.Fatalf("expected test %v with 5 retries, got %+v", want, s.HealthCheck)
+				}
+			},
+		},
+		{
+			name: "ports long syntax",
+			in: `ports:
+    - "3128:3128"
+    - target: 80
+    - target: 8080
+      published: 8080
+    - target: 53
+      published: "10053"
+      host_ip: 127.0.0.1
+      protocol: udp
+    - target: 443
+      host_ip: ::1
+`,
+			check: func(t *testing.T, s *Service) {
+				t.Helper()
+				want := []string{"3128:3128", "80", "8080:8080", "127.0.0.1:10053:53/udp", "[::1]::443"}
+				if !slices.Equal(s.Ports, want) {
+					t.Fatalf("expected ports %v, got %v", want, s.Ports)
+				}
+			},
+		},
+	}
+
+	for i := range tests {
+		tc := &tests[i]
+		t.Run(tc.name, func(t *testing.T) {
+			in := "services:\n    proxy:\n        image: proxy\n" + indent(tc.in, "        ") + tc.top
+			c, err := Load(strings.NewReader(in))
+			if err != nil {
+				t.Fatal(err)
+			}
+			tc.check(t, c.Services["proxy"])
+
+			var b bytes.Buffer
+			if _, err := c.WriteTo(&b); err != nil {
+				t.Fatal(err)
+			}
+			rc, err := Load(&b)
+			if err != nil {
+				t.Fatalf("round trip: %v", err)
+			}
+			tc.check(t, rc.Services["proxy"])
+		})
+	}
+}
+
+func indent(s, prefix string) string {
+	lines := strings.SplitAfter(s, "\n")
+	for i, l := range lines {
+		if l != "" {
+			lines[i] = prefix + l
+		}
+	}
+	return strings.Join(lines, "")
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		err  string
+	}{
+		{
+			name: "unknown field",
+			in:   "services:\n    proxy:\n        image: proxy\n        foo: bar\n",
+			err:  "line 4: field foo not found",
+		},
+		{
+			name: "unknown nested field",
+			in:   "services:\n    proxy:\n        image: proxy\n        healthcheck:\n            test: [CMD, 'true']\n            foo: bar\n",
+			err:  "line 6: field foo not found",
+		},
+		{
+			name: "unknown dependency",
+			in:   "services:\n    proxy:\n        image: proxy\n        depends_on: [upstream]\n",
+			err:  "service proxy depends on unknown service upstream",
+		},
+		{
+			name: "environment list without value",
+			in:   "services:\n    proxy:\n        image: proxy\n        environment:\n            - FOO=bar\n            - HOME\n",
+			err:  `line 6: environment "HOME": expected KEY=VALUE`,
+		},
+		{
+			name: "ports long syntax unsupported field",
+			in:   "services:\n    proxy:\n        image: proxy\n        ports:\n            - target: 80\n              mode: host\n",
+			err:  "line 6: field mode not found",
+		},
+		{
+			name: "ports long syntax without target",
+			in:   "services:\n    proxy:\n        image: proxy\n        ports:\n            - published: 80\n",
+			err:  "line 5: port target is empty",
+		},
+		{
+			name: "ports long syntax invalid protocol",
+			in:   "services:\n    proxy:\n        image: proxy\n        ports:\n            - target: 80\n              protocol: icmp\n",
+			err:  `invalid protocol "icmp"`,
+		},
+		{
+			name: "unknown top-level field",
+			in:   "foo: bar\n",
+			err:  "field foo not found",
+		},
+	}
+
+	for i := range tests {
+		tc := &tests[i]
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Load(strings.NewReader(tc.in))
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Fatalf("expected error to contain %q, got %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestServiceValidateRestart(t *testing.T) {
+	s := &Service{
+		Name:    "svc",
+		Image:   "img",
+		Restart: "unless-stopped",
+		Labels:  map[string]string{"com.example.test": "true"},
+	}
+	if err := s.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	s.Restart = "sometimes"
+	if err := s.Validate(); err == nil || !strings.Contains(err.Error(), "restart policy") {
+		t.Fatalf("expected restart policy error, got %v", err)
+	}
+}
+
+func TestServiceBuild(t *testing.T) {
+	c := New()
+	if err := c.AddService(&Service{
+		Name: "proxy",
+		Build: &Build{
+			Context:    "/src",
+			Dockerfile: "Containerfile",
+			Args:       map[string]string{"BASE_IMAGE": "ubuntu"},
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	if _, err := c.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	want := "build:\n            context: /src\n            dockerfile: Containerfile\n            args:\n                BASE_IMAGE: ubuntu\n"
+	if !strings.Contains(b.String(), want) {
+		t.Fatalf("expected %q in:\n%s", want, b.String())
+	}
+
+	if err := (&Service{Name: "empty", Build: &Build{}}).Validate(); err == nil {
+		t.Fatal("expected error for service without image and build context")
+	}
+}
+
+func TestBuilderUndeclaredNetwork(t *testing.T) {
+	s := func() *Service {
+		return &Service{
+			Name:    "svc",
+			Image:   "img",
+			Network: map[string]ServiceNetwork{"internal": {}},
+		}
+	}
+
+	if _, err := NewBuilder().AddService(s()).Build(); err == nil || !strings.Contains(err.Error(), "undeclared network internal") {
+		t.Fatalf("expected undeclared network error, got %v", err)
+	}
+
+	_, err := NewBuilder().
+		AddService(s()).
+		AddNetwork(&Network{Name: "internal", Driver: "bridge"}).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestServiceCommandArgs(t *testing.T) {
+	c := New()
+	if err := c.AddService(&Service{
+		Name:        "proxy",
+		Image:       "proxy",
+		CommandArgs: []string{"run", "--pac", "/path with spaces/pac.js"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	if _, err := c.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	want := "command:\n            - run\n            - --pac\n            - /path with spaces/pac.js\n"
+	if !strings.Contains(b.String(), want) {
+		t.Fatalf("expected %q in:\n%s", want, b.String())
+	}
+
+	s := &Service{
+		Name:        "svc",
+		Image:       "img",
+		Command:     "run",
+		CommandArgs: []string{"run"},
+	}
+	if err := s.Validate(); err == nil || !strings.Contains(err.Error(), "mutually exclusive") {
+		t.Fatalf("expected mutually exclusive error, got %v", err)
+	}
+}
+
+func TestServiceEnvFile(t *testing.T) {
+	f := filepath.Join(t.TempDir(), "proxy.env")
+	if err := os.WriteFile(f, []byte("FORWARDER_LOG_LEVEL=debug\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := NewBuilder().AddService(&Service{
+		Name:    "proxy",
+		Image:   "proxy",
+		EnvFile: []string{f},
+	}).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	if _, err := c.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	if want := "env_file:\n            - " + f + "\n"; !strings.Contains(b.String(), want) {
+		t.Fatalf("expected %q in:\n%s", want, b.String())
+	}
+
+	_, err = NewBuilder().AddService(&Service{
+		Name:    "proxy",
+		Image:   "proxy",
+		EnvFile: []string{filepath.Join(t.TempDir(), "missing.env")},
+	}).Build()
+	if err == nil {
+		t.Fatal("expected error for missing env file")
+	}
+}
+
+func TestServiceEnvFileSkipCheck(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing.env")
+
+	tests := []struct {
+		name string
+		skip bool
+		err  string
+	}{
+		{
+			name: "check",
+			err:  "service proxy env file",
+		},
+		{
+			name: "skip",
+			skip: true,
+		},
+	}
+
+	for i := range tests {
+		tc := &tests[i]
+		t.Run(tc.name, func(t *testing.T) {
+			b := NewBuilder()
+			if tc.skip {
+				b.SkipEnvFileCheck()
+			}
+			c, err := b.AddService(&Service{
+				Name:    "proxy",
+				Image:   "proxy",
+				EnvFile: []string{missing},
+			}).Build()
+
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("expected error to contain %q, got %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := c.Services["proxy"].EnvFile; len(got) != 1 || got[0] != missing {
+				t.Fatalf("expected env file %q, got %v", missing, got)
+			}
+		})
+	}
+}
+
+func TestServiceExposeExtraHosts(t *testing.T) {
+	c := New()
+	if err := c.AddService(&Service{
+		Name:       "proxy",
+		Image:      "proxy",
+		Expose:     []string{"3128", "10000-10010/udp"},
+		ExtraHosts: []string{"host.docker.internal:host-gateway", "upstream:192.168.1.1", "v6:::1"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	if _, err := c.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"expose:\n            - \"3128\"\n            - 10000-10010/udp\n",
+		"extra_hosts:\n            - host.docker.internal:host-gateway\n            - upstream:192.168.1.1\n",
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Fatalf("expected %q in:\n%s", want, b.String())
+		}
+	}
+
+	for _, s := range []*Service{
+		{Name: "svc", Image: "img", Expose: []string{"31x8"}},
+		{Name: "svc", Image: "img", Expose: []string{"0"}},
+		{Name: "svc", Image: "img", ExtraHosts: []string{"upstream"}},
+		{Name: "svc", Image: "img", ExtraHosts: []string{"upstream:not-an-ip"}},
+	} {
+		if err := s.Validate(); err == nil {
+			t.Errorf("expected error for %+v", s)
+		}
+	}
+}
+
+func TestServiceValidatePorts(t *testing.T) {
+	tests := []struct {
+		port string
+		err  string
+	}{
+		{port: "8080"},
+		{port: "3128:3128"},
+		{port: "0:8080"},
+		{port: ":8080"},
+		{port: "127.0.0.1:3128:3128"},
+		{port: "127.0.0.1::3128"},
+		{port: "[::1]:3128:3128"},
+		{port: "10000-10010:10000-10010/udp"},
+		{port: "808O:8080", err: `invalid port "808O"`},
+		{port: "8080:70000", err: `invalid port "70000"`},
+		{port: "8080:0", err: "port cannot be 0"},
+		{port: "8080/http", err: `invalid protocol "http"`},
+		{port: "localhost:3128:3128", err: `invalid IP address "localhost"`},
+		{port: "1:2:3:4", err: "expected [[ip:]host:]container"},
+	}
+
+	for i := range tests {
+		tc := &tests[i]
+		t.Run(tc.port, func(t *testing.T) {
+			s := &Service{Name: "svc", Image: "img", Ports: []string{tc.port}}
+			err := s.Validate()
+			if tc.err == "" {
+				if err != nil {
+					t.Fatalf("expected success, got %q", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Fatalf("expected error to contain %q, got %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestServiceValidateVolumes(t *testing.T) {
+	tests := []struct {
+		volume string
+		err    string
+	}{
+		{volume: "/data"},
+		{volume: "data:/data"},
+		{volume: "/host/data:/data"},
+		{volume: "/host/data:/data:ro"},
+		{volume: "/host/data:/data:rw,Z"},
+		{volume: "./data:/data", err: "must be a named volume or an absolute path"},
+		{volume: "data/dir:/data", err: "must be a named volume or an absolute path"},
+		{volume: "/host/data:data", err: "must be an absolute path"},
+		{volume: "/host/data:/data:rx", err: `invalid mode "rx"`},
+		{volume: "/a:/b:ro:z", err: "expected [source:]target[:mode]"},
+	}
+
+	for i := range tests {
+		tc := &tests[i]
+		t.Run(tc.volume, func(t *testing.T) {
+			s := &Service{Name: "svc", Image: "img", Volumes: []string{tc.volume}}
+			err := s.Validate()
+			if tc.err == "" {
+				if err != nil {
+					t.Fatalf("expected success, got %q", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Fatalf("expected error to contain %q, got %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestBuilderVolumes(t *testing.T) {
+	c, err := NewBuilder().AddService(&Service{
+		Name:    "svc",
+		Image:   "img",
+		Volumes: []string{"./data:/data:ro", "named:/named", "/anonymous"},
+	}).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v := c.Services["svc"].Volumes
+	if want := path.Join(curDir(), "data") + ":/data:ro"; v[0] != want {
+		t.Errorf("expected %q, got %q", want, v[0])
+	}
+	if v[1] != "named:/named" || v[2] != "/anonymous" {
+		t.Errorf("unexpected volumes %v", v)
+	}
+}
@@ -8,7 +8,16 @@ package compose
 
 import (
 	"errors"
+	"fmt"
+	"net"
+	"path"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
+
+	"gopkg.in/yaml.v3"
 )
 
 type ServiceNetwork struct {
@@ -16,31 +25,310 @@ type ServiceNetwork struct {
 }
 
 type Service struct {
-	Name        string                    `yaml:"-"`
-	Image       string                    `yaml:"image,omitempty"`
-	Command     string                    `yaml:"command,omitempty"`
-	Environment map[string]string         `yaml:"environment,omitempty"`
-	Ports       []string                  `yaml:"ports,omitempty"`
-	Volumes     []string                  `yaml:"volumes,omitempty"`
-	HealthCheck *HealthCheck              `yaml:"healthcheck,omitempty"`
-	Network     map[string]ServiceNetwork `yaml:"networks,omitempty"`
-	Privileged  bool                      `yaml:"privileged,omitempty"`
+	Name          string                    `yaml:"-"`
+	ContainerName string                    `yaml:"container_name,omitempty"`
+	Image         string                    `yaml:"image,omitempty"`
+	Build         *Build                    `yaml:"build,omitempty"`
+	Command       string                    `yaml:"command,omitempty"`
+	CommandArgs   []string                  `yaml:"-"`
+	Environment   map[string]string         `yaml:"environment,omitempty"`
+	EnvFile       []string                  `yaml:"env_file,omitempty"` // Environment takes precedence over EnvFile.
+	Ports         []string                  `yaml:"ports,omitempty"`
+	Expose        []string                  `yaml:"expose,omitempty"`
+	ExtraHosts    []string                  `yaml:"extra_hosts,omitempty"`
+	Volumes       []string                  `yaml:"volumes,omitempty"`
+	HealthCheck   *HealthCheck              `yaml:"healthcheck,omitempty"`
+	Network       map[string]ServiceNetwork `yaml:"networks,omitempty"`
+	Privileged    bool                      `yaml:"privileged,omitempty"`
+	DependsOn     map[string]Dependency     `yaml:"depends_on,omitempty"`
+	Restart       string                    `yaml:"restart,omitempty"`
+	Labels        map[string]string         `yaml:"labels,omitempty"`
+}
+
+// MarshalYAML writes CommandArgs in the exec form i.e. a list of arguments.
+func (s *Service) MarshalYAML() (any, error) {
+	type service Service
+
+	if len(s.CommandArgs) == 0 {
+		return (*service)(s), nil
+	}
+
+	var n yaml.Node
+	if err := n.Encode((*service)(s)); err != nil {
+		return nil, err
+	}
+	var args yaml.Node
+	if err := args.Encode(s.CommandArgs); err != nil {
+		return nil, err
+	}
+	n.Content = append(n.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: "command"}, &args)
+
+	return &n, nil
+}
+
+// UnmarshalYAML reads the command in the string form to Command and in the exec form to CommandArgs.
+// The short form of depends_on i.e. a list of service names is read with empty conditions.
+// The list forms of environment i.e. KEY=VALUE items and networks i.e. network names are read to maps,
+// environment variables without a value are not supported.
+// Ports in the long syntax are converted to the short syntax,
+// only the target, published, host_ip and protocol fields are supported.
+// Unknown fields result in an error.
+func (s *Service) UnmarshalYAML(n *yaml.Node) error {
+	type service Service
+
+	if err := checkKnownFields(n, reflect.TypeOf(s)); err != nil {
+		return err
+	}
+
+	if n.Kind == yaml.MappingNode {
+		m := *n
+		m.Content = nil
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			k, v := n.Content[i], n.Content[i+1]
+			if v.Kind == yaml.SequenceNode {
+				switch k.Value {
+				case "command":
+					if err := v.Decode(&s.CommandArgs); err != nil {
+						return err
+					}
+					continue
+				case "depends_on":
+					var names []string
+					if err := v.Decode(&names); err != nil {
+						return err
+					}
+					s.DependsOn = make(map[string]Dependency, len(names))
+					for _, name := range names {
+						s.DependsOn[name] = Dependency{}
+					}
+					continue
+				case "environment":
+					env, err := decodeEnvironmentList(v)
+					if err != nil {
+						return err
+					}
+					s.Environment = env
+					continue
+				case "networks":
+					var names []string
+					if err := v.Decode(&names); err != nil {
+						return err
+					}
+					s.Network = make(map[string]ServiceNetwork, len(names))
+					for _, name := range names {
+						s.Network[name] = ServiceNetwork{}
+					}
+					continue
+				case "ports":
+					ports, err := decodePorts(v)
+					if err != nil {
+						return err
+					}
+					s.Ports = ports
+					continue
+				}
+			}
+			m.Content = append(m.Content, k, v)
+		}
+		n = &m
+	}
+
+	return n.Decode((*service)(s))
+}
+
+// decodeEnvironmentList reads the list form of environment i.e. KEY=VALUE items.
+func decodeEnvironmentList(n *yaml.Node) (map[string]string, error) {
+	var list []string
+	if err := n.Decode(&list); err != nil {
+		return nil, err
+	}
+
+	env := make(map[string]string, len(list))
+	for i, kv := range list {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok || k == "" {
+			return nil, fmt.Errorf("line %d: environment %q: expected KEY=VALUE, variables without a value are not supported",
+				n.Content[i].Line, kv)
+		}
+		env[k] = v
+	}
+	return env, nil
+}
+
+// servicePort is the long syntax of a published port.
+type servicePort struct {
+	Target    string `yaml:"target"`
+	Published string `yaml:"published,omitempty"`
+	HostIP    string `yaml:"host_ip,omitempty"`
+	Protocol  string `yaml:"protocol,omitempty"`
+}
+
+// shortSyntax returns the port in the short syntax [[ip:]host:]container[/protocol].
+func (p *servicePort) shortSyntax() string {
+	v := p.Target
+	if p.Published != "" || p.HostIP != "" {
+		v = p.Published + ":" + v
+	}
+	if p.HostIP != "" {
+		ip := p.HostIP
+		if strings.Contains(ip, ":") {
+			ip = "[" + ip + "]"
+		}
+		v = ip + ":" + v
+	}
+	if p.Protocol != "" {
+		v += "/" + p.Protocol
+	}
+	return v
+}
+
+// decodePorts reads ports in the short and long syntax, the long syntax is converted to the short syntax.
+func decodePorts(n *yaml.Node) ([]string, error) {
+	ports := make([]string, 0, len(n.Content))
+	for _, v := range n.Content {
+		if v.Kind != yaml.MappingNode {
+			var p string
+			if err := v.Decode(&p); err != nil {
+				return nil, err
+			}
+			ports = append(ports, p)
+			continue
+		}
+
+		var p servicePort
+		if err := checkKnownFields(v, reflect.TypeOf(p)); err != nil {
+			return nil, fmt.Errorf("port: %w", err)
+		}
+		if err := v.Decode(&p); err != nil {
+			return nil, err
+		}
+		if p.Target == "" {
+			return nil, fmt.Errorf("line %d: port target is empty", v.Line)
+		}
+		ports = append(ports, p.shortSyntax())
+	}
+	return ports, nil
+}
+
+// checkKnownFields returns an error if a mapping in n has a key that is not a YAML field of the corresponding struct.
+// It is needed because yaml.Node.Decode used by UnmarshalYAML does not support yaml.Decoder.KnownFields.
+func checkKnownFields(n *yaml.Node, t reflect.Type) error {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch {
+	case n.Kind == yaml.MappingNode && t.Kind() == reflect.Struct:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			k := n.Content[i]
+			f, ok := yamlField(t, k.Value)
+			if !ok {
+				return fmt.Errorf("line %d: field %s not found in type %s", k.Line, k.Value, t)
+			}
+			if err := checkKnownFields(n.Content[i+1], f.Type); err != nil {
+				return err
+			}
+		}
+	case n.Kind == yaml.MappingNode && t.Kind() == reflect.Map:
+		for i := 1; i < len(n.Content); i += 2 {
+			if err := checkKnownFields(n.Content[i], t.Elem()); err != nil {
+				return err
+			}
+		}
+	case n.Kind == yaml.SequenceNode && t.Kind() == reflect.Slice:
+		for _, v := range n.Content {
+			if err := checkKnownFields(v, t.Elem()); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
 }
 
+func yamlField(t reflect.Type, key string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if name, _, _ := strings.Cut(f.Tag.Get("yaml"), ","); name == key && name != "-" {
+			return f, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+var restartPolicies = []string{"no", "always", "on-failure", "unless-stopped"}
+
 func (s *Service) Validate() error {
 	if s == nil {
 		return errors.New("service is nil")
 	}
-	if s.Image == "" {
-		return errors.New("service image is empty")
+	if s.Image == "" && (s.Build == nil || s.Build.Context == "") {
+		return errors.New("service image and build context are empty")
 	}
 	if s.Name == "" {
 		return errors.New("service name is empty")
 	}
+	for i, f := range s.EnvFile {
+		if f == "" {
+			return fmt.Errorf("service %s env file at pos %d is empty", s.Name, i)
+		}
+	}
+	for _, p := range s.Ports {
+		if err := validatePublishedPort(p); err != nil {
+			return fmt.Errorf("service %s port %q: %w", s.Name, p, err)
+		}
+	}
+	for _, v := range s.Volumes {
+		if err := validateVolume(v); err != nil {
+			return fmt.Errorf("service %s volume %q: %w", s.Name, v, err)
+		}
+	}
+	for _, e := range s.Expose {
+		p, _, _ := strings.Cut(e, "/")
+		if err := validatePortRange(p); err != nil {
+			return fmt.Errorf("service %s expose %q: %w", s.Name, e, err)
+		}
+	}
+	for _, h := range s.ExtraHosts {
+		if err := validateExtraHost(h); err != nil {
+			return fmt.Errorf("service %s extra host %q: %w", s.Name, h, err)
+		}
+	}
+	if s.Command != "" && len(s.CommandArgs) > 0 {
+		return fmt.Errorf("service %s command and command args are mutually exclusive", s.Name)
+	}
+	if s.Restart != "" && !slices.Contains(restartPolicies, s.Restart) {
+		return fmt.Errorf("service %s restart policy %q is invalid, expected one of: %s",
+			s.Name, s.Restart, strings.Join(restartPolicies, ", "))
+	}
+	if s.HealthCheck != nil {
+		if err := s.HealthCheck.Validate(); err != nil {
+			return fmt.Errorf("service %s health check: %w", s.Name, err)
+		}
+	}
 
 	return nil
 }
 
+// Build specifies how to build the service image, if Image is set the built image is tagged with it.
+type Build struct {
+	Context    string            `yaml:"context"`
+	Dockerfile string            `yaml:"dockerfile,omitempty"`
+	Args       map[string]string `yaml:"args,omitempty"`
+}
+
+const (
+	ServiceStarted = "service_started"
+	ServiceHealthy = "service_healthy"
+)
+
+// Dependency is a long form of a service dependency.
+// If Condition is empty, it is set by Compose.AddService to ServiceHealthy
+// if the dependency has a health check and ServiceStarted otherwise.
+type Dependency struct {
+	Condition string `yaml:"condition"`
+}
+
 type HealthCheck struct {
 	Test []string `yaml:"test,omitempty"`
 	// Interval between two health checks, the default is 30 seconds.
@@ -53,3 +341,153 @@ type HealthCheck struct {
 	// The number of seconds to start the health check after the container starts, the default is 0 seconds.
 	StartPeriod time.Duration `yaml:"start_period,omitempty"`
 }
+
+// UnmarshalYAML reads the string form of test as a shell command i.e. ["CMD-SHELL", test].
+func (h *HealthCheck) UnmarshalYAML(n *yaml.Node) error {
+	type healthCheck HealthCheck
+
+	if n.Kind == yaml.MappingNode {
+		m := *n
+		m.Content = nil
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			k, v := n.Content[i], n.Content[i+1]
+			if k.Value == "test" && v.Kind == yaml.ScalarNode {
+				if v.Value != "" {
+					h.Test = []string{"CMD-SHELL", v.Value}
+				}
+				continue
+			}
+			m.Content = append(m.Content, k, v)
+		}
+		n = &m
+	}
+
+	return n.Decode((*healthCheck)(h))
+}
+
+// Validate checks that the health check has a test command.
+// Services with a health check are reported healthy by Command.Wait only after the test succeeds.
+func (h *HealthCheck) Validate() error {
+	if len(h.Test) == 0 {
+		return errors.New("test is empty")
+	}
+	return nil
+}
+
+func validatePort(p string) error {
+	v, err := strconv.ParseUint(p, 10, 16)
+	if err != nil {
+		return fmt.Errorf("invalid port %q", p)
+	}
+	if v == 0 {
+		return errors.New("port cannot be 0")
+	}
+	return nil
+}
+
+// validatePortRange validates a port or a port range i.e. 8000-8010.
+func validatePortRange(p string) error {
+	from, to, ok := strings.Cut(p, "-")
+	if err := validatePort(from); err != nil {
+		return err
+	}
+	if ok {
+		return validatePort(to)
+	}
+	return nil
+}
+
+// validatePublishedPort validates the short syntax of a published port: [[ip:]host:]container[/protocol].
+// The host port can be empty or 0 to let the runtime allocate it.
+func validatePublishedPort(p string) error {
+	p, proto, ok := strings.Cut(p, "/")
+	if ok && proto != "tcp" && proto != "udp" && proto != "sctp" {
+		return fmt.Errorf("invalid protocol %q", proto)
+	}
+
+	// Strip IPv6 address in brackets.
+	if strings.HasPrefix(p, "[") {
+		ip, rest, ok := strings.Cut(p[1:], "]:")
+		if !ok || net.ParseIP(ip) == nil {
+			return errors.New("invalid IP address")
+		}
+		p = rest
+		if !strings.Contains(p, ":") {
+			return errors.New("expected ip:host:container")
+		}
+	}
+
+	a := strings.Split(p, ":")
+	switch len(a) {
+	case 1, 2:
+	case 3:
+		if net.ParseIP(a[0]) == nil {
+			return fmt.Errorf("invalid IP address %q", a[0])
+		}
+		a = a[1:]
+	default:
+		return errors.New("expected [[ip:]host:]container")
+	}
+
+	if len(a) == 2 && a[0] != "" && a[0] != "0" {
+		if err := validatePortRange(a[0]); err != nil {
+			return err
+		}
+	}
+	return validatePortRange(a[len(a)-1])
+}
+
+var volumeModes = []string{"ro", "rw", "z", "Z"}
+
+// validateVolume validates the short syntax of a volume: [source:]target[:mode].
+// Source is a named volume or an absolute host path, relative paths are made absolute by Builder.
+func validateVolume(v string) error {
+	a := strings.Split(v, ":")
+	if len(a) > 3 {
+		return errors.New("expected [source:]target[:mode]")
+	}
+
+	var src, dst, mode string
+	switch len(a) {
+	case 1:
+		dst = a[0]
+	case 2:
+		src, dst = a[0], a[1]
+	case 3:
+		src, dst, mode = a[0], a[1], a[2]
+	}
+
+	if src != "" && !path.IsAbs(src) && !isVolumeName(src) {
+		return fmt.Errorf("source %q must be a named volume or an absolute path", src)
+	}
+	if !path.IsAbs(dst) {
+		return fmt.Errorf("target %q must be an absolute path", dst)
+	}
+	if mode != "" {
+		for _, m := range strings.Split(mode, ",") {
+			if !slices.Contains(volumeModes, m) {
+				return fmt.Errorf("invalid mode %q, expected one of: %s", m, strings.Join(volumeModes, ", "))
+			}
+		}
+	}
+	return nil
+}
+
+func isVolumeName(s string) bool {
+	return s != "" && !strings.HasPrefix(s, ".") && !strings.ContainsAny(s, `/\`)
+}
+
+// validateExtraHost validates host:ip entry, the IP can be the special host-gateway value.
+func validateExtraHost(h string) error {
+	name, ip, ok := strings.Cut(h, ":")
+	if !ok || name == "" {
+		return errors.New("expected host:ip")
+	}
+	if ip == "host-gateway" {
+		return nil
+	}
+	if net.ParseIP(strings.Trim(ip, "[]")) == nil {
+		return fmt.Errorf("invalid IP %q", ip)
+	}
+	return nil
+}
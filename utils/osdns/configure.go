@@ -7,6 +7,7 @@ package osdns
 import (
 	"errors"
 	"fmt"
+	"strings"
 )
 
 func Configure(cfg *Config) error {
@@ -28,14 +29,26 @@ func configure(cfg *Config) error {
 		return fmt.Errorf("failed to get system DNS config: %w", procDNSCfg.err)
 	}
 
-	procDNSCfg.servers = make([]string, len(cfg.Servers))
-	for i := range cfg.Servers {
-		procDNSCfg.servers[i] = cfg.Servers[i].String()
+	if len(cfg.Servers) > 0 {
+		procDNSCfg.servers = make([]string, len(cfg.Servers))
+		for i := range cfg.Servers {
+			procDNSCfg.servers[i] = cfg.Servers[i].String()
+		}
+		procDNSCfg.timeout = cfg.Timeout
+		procDNSCfg.rotate = cfg.RoundRobin
+	}
+	if len(cfg.Search) > 0 {
+		procDNSCfg.search = make([]string, len(cfg.Search))
+		for i, s := range cfg.Search {
+			if !strings.HasSuffix(s, ".") {
+				s += "."
+			}
+			procDNSCfg.search[i] = s
+		}
 	}
-	procDNSCfg.timeout = cfg.Timeout
-	procDNSCfg.rotate = cfg.RoundRobin
 
-	// Disable config reload from system dns config file (/etc/resolv.conf).
+	// Disable config reload from system dns config file (/etc/resolv.conf),
+	// a reload would discard the overridden servers and search domains.
 	procDNSCfg.noReload = true
 
 	resolvConf.dnsConfig.Store(procDNSCfg)
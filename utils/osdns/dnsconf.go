@@ -38,6 +38,8 @@ type Config struct {
 	Servers    []netip.AddrPort
 	Timeout    time.Duration
 	RoundRobin bool
+	// Search is a list of domains appended to names with fewer dots than ndots (usually 1) during lookup.
+	Search []string
 }
 
 func DefaultConfig() *Config {
@@ -45,3 +47,24 @@ func DefaultConfig() *Config {
 		Timeout: 5 * time.Second,
 	}
 }
+
+// RemoveDuplicateServers removes duplicate servers preserving the order of the first occurrence.
+// It returns the removed servers.
+func (c *Config) RemoveDuplicateServers() []netip.AddrPort {
+	var (
+		seen    = make(map[netip.AddrPort]struct{}, len(c.Servers))
+		servers = c.Servers[:0]
+		dups    []netip.AddrPort
+	)
+	for _, s := range c.Servers {
+		if _, ok := seen[s]; ok {
+			dups = append(dups, s)
+			continue
+		}
+		seen[s] = struct{}{}
+		servers = append(servers, s)
+	}
+	c.Servers = servers
+
+	return dups
+}
@@ -2,6 +2,7 @@ package osdns
 
 import (
 	"go/build"
+	"net/netip"
 	"path/filepath"
 	"testing"
 
@@ -28,3 +29,28 @@ func TestDNSConfigIntegrity(t *testing.T) {
 		t.Fatalf("dns configs are not equal %s", diff)
 	}
 }
+
+func TestConfigRemoveDuplicateServers(t *testing.T) {
+	c := DefaultConfig()
+	c.Servers = []netip.AddrPort{
+		netip.MustParseAddrPort("1.1.1.1:53"),
+		netip.MustParseAddrPort("8.8.8.8:53"),
+		netip.MustParseAddrPort("1.1.1.1:53"),
+		netip.MustParseAddrPort("1.1.1.1:5353"),
+		netip.MustParseAddrPort("8.8.8.8:53"),
+	}
+
+	dups := c.RemoveDuplicateServers()
+
+	want := []netip.AddrPort{
+		netip.MustParseAddrPort("1.1.1.1:53"),
+		netip.MustParseAddrPort("8.8.8.8:53"),
+		netip.MustParseAddrPort("1.1.1.1:5353"),
+	}
+	if diff := cmp.Diff(want, c.Servers, cmp.Comparer(func(a, b netip.AddrPort) bool { return a == b })); diff != "" {
+		t.Fatalf("unexpected servers (-want +got):\n%s", diff)
+	}
+	if len(dups) != 2 {
+		t.Fatalf("expected 2 duplicates, got %v", dups)
+	}
+}